@@ -5,9 +5,15 @@ import (
 	"log"
 
 	"github.com/Koops0/GPSXE/bios"
+	"github.com/Koops0/GPSXE/cdrom"
 	"github.com/Koops0/GPSXE/dma"
 	"github.com/Koops0/GPSXE/gpu"
+	"github.com/Koops0/GPSXE/irq"
 	"github.com/Koops0/GPSXE/ram"
+	"github.com/Koops0/GPSXE/sio"
+	"github.com/Koops0/GPSXE/spu"
+	"github.com/Koops0/GPSXE/timers"
+	"github.com/Koops0/GPSXE/tty"
 )
 
 type Range struct {
@@ -36,7 +42,7 @@ var CACHECONTROL = Range{
 }
 
 var RAM = Range{
-	address: 0xa0000000,
+	address: 0x00000000,
 	bit:     2 * 1024 * 1024,
 }
 
@@ -63,14 +69,24 @@ var EX1 = Range{
 	bit:     66,
 }
 
+var SIO0 = Range{ //Controllers and memory cards
+	address: 0x1f801040,
+	bit:     0x10,
+}
+
 var IRQ_CONTROL = Range{
 	address: 0x1f801070,
 	bit:     8,
 }
 
-var TIMERS = Range{ //Change?
-	address: 0x1f801104,
-	bit:     8,
+var TIMERS = Range{
+	address: 0x1f801100,
+	bit:     0x30,
+}
+
+var CDROM = Range{
+	address: 0x1f801800,
+	bit:     4,
 }
 
 var DMA = Range{
@@ -78,9 +94,9 @@ var DMA = Range{
 	bit:     0x80,
 }
 
-var GPU = Range{ //Change?
-	address: 0x1f801814,
-	bit:     32,
+var GPU = Range{ //GP0/GPUREAD, then GP1/GPUSTAT
+	address: 0x1f801810,
+	bit:     8,
 }
 
 func (r Range) Contains(addr uint32) *uint32 { //Return offset if it exists
@@ -97,15 +113,75 @@ type Interconnect struct {
 	ram  ram.RAM
 	dma  dma.DMA
 	gpu  gpu.GPU
+	irq    *irq.InterruptState
+	timers *timers.Timers
+	cdrom  *cdrom.CdRom
+	spu    *spu.SPU
+	sio0   *sio.SIO0
+	tty    *tty.TTY //Console capture, nil when disabled
+	watch  []Watcher //Debugger and tracer hooks
 }
 
 func (i Interconnect) New(bios *bios.BIOS, gpu gpu.GPU) Interconnect {
 	i.bios = *bios
+	i.ram = i.ram.New()
 	i.dma.New()
 	i.gpu = gpu
+	i.irq = irq.New()
+	i.timers = timers.New(i.irq)
+	i.cdrom = cdrom.New(i.irq)
+	i.spu = spu.New(i.irq)
+	i.sio0 = sio.New(i.irq)
 	return i
 }
 
+func (i *Interconnect) SetTTY(t *tty.TTY) {
+	i.tty = t
+}
+
+func (i *Interconnect) TTY() *tty.TTY {
+	return i.tty
+}
+
+func (i *Interconnect) Ram() *ram.RAM {
+	return &i.ram
+}
+
+func (i *Interconnect) Gpu() *gpu.GPU {
+	return &i.gpu
+}
+
+func (i *Interconnect) CdRom() *cdrom.CdRom {
+	return i.cdrom
+}
+
+func (i *Interconnect) Spu() *spu.SPU {
+	return i.spu
+}
+
+func (i *Interconnect) Sio0() *sio.SIO0 {
+	return i.sio0
+}
+
+func (i *Interconnect) Tick(cycles uint32) { //Advance the peripherals by CPU cycles
+	clocks := i.gpu.Tick(cycles)
+	if clocks.VBlanks > 0 {
+		i.irq.Assert(irq.VBlank)
+	}
+
+	i.timers.Tick(cycles)
+	i.timers.Dotclock(clocks.Dots)
+	i.timers.HBlank(clocks.HBlanks, clocks.InHBlank)
+	i.timers.VBlank(clocks.VBlanks, clocks.InVBlank)
+	i.cdrom.Tick(cycles)
+	i.spu.Tick(cycles)
+	i.sio0.Tick(cycles)
+}
+
+func (i *Interconnect) Irq() *irq.InterruptState { //Interrupt controller
+	return i.irq
+}
+
 func (i *Interconnect) Dma_reg(offset uint32) uint32 { //DMA reg read
 	major := (offset & 0x70) >> 4
 	minor := offset & 0xf
@@ -138,7 +214,7 @@ func (i *Interconnect) Set_dma_reg(offset uint32, val uint32) { //DMA reg write
 	major := (offset & 0x70) >> 4
 	minor := offset & 0xf
 
-	var active_port dma.Port
+	active_port := dma.Port(-1)
 
 	switch major {
 	case 0, 1, 2, 3, 4, 5, 6:
@@ -164,9 +240,9 @@ func (i *Interconnect) Set_dma_reg(offset uint32, val uint32) { //DMA reg write
 	case 7:
 		switch minor {
 		case 0:
-			i.dma.Control()
+			i.dma.Set_control(val)
 		case 4:
-			i.dma.Interrupt()
+			i.dma.Set_interrupt(val)
 		default:
 			panic("Unhandled DMA Write")
 		}
@@ -220,7 +296,13 @@ func (i *Interconnect) DoDMALinkedList(port dma.Port) {
 		addr = header & 0x1ffffc
 	}
 
-	channel.Done()
+	i.DmaDone(port)
+}
+
+func (i *Interconnect) DmaDone(port dma.Port) { //Channel finished, raise IRQ3 if enabled
+	if i.dma.Done(port) {
+		i.irq.Assert(irq.Dma)
+	}
 }
 
 func (i *Interconnect) DoDMABlock(port dma.Port) {
@@ -264,13 +346,26 @@ func (i *Interconnect) DoDMABlock(port dma.Port) {
 					src_word = Wrapping_sub(addr, 4, 32) & 0x1fffff
 				}
 				// Hypothetical use of src_word, e.g., writing to memory
-				i.Store32(cur_addr, src_word)
+				i.ram.Store32(cur_addr, src_word)
+			case dma.Cdrom:
+				src_word = i.cdrom.DmaRead()
+				i.ram.Store32(cur_addr, src_word)
+			case dma.Gpu: //VRAM to CPU, the GPU must be in VRAMCPU mode
+				src_word = i.gpu.Read()
+				i.ram.Store32(cur_addr, src_word)
+			case dma.Spu:
+				src_word = i.spu.DmaRead()
+				i.ram.Store32(cur_addr, src_word)
+			default:
+				panic("Unhandled DMA port")
 			}
 		case dma.FromRam:
 			src_word = i.ram.Load32(cur_addr)
 			switch port {
 			case dma.Gpu:
 				i.gpu.Gp0(src_word)
+			case dma.Spu:
+				i.spu.DmaWrite(src_word)
 			default:
 				panic("Unhandled DMA port")
 			}
@@ -279,29 +374,35 @@ func (i *Interconnect) DoDMABlock(port dma.Port) {
 		addr = Wrapping_add(addr, uint32(increment), 32)
 		remsz--
 	}
-	channel.Done()
+	i.DmaDone(port)
 }
 
-func (i *Interconnect) Load32(addr uint32) uint32 { //load 32-bit at addr
+func (i *Interconnect) load32(addr uint32) uint32 { //load 32-bit at addr
 	abaddr := Mask_region(addr)
 
 	if addr%4 != 0 {
 		panic("Load32 address alignment error")
+	} else if offset := RAM.Contains(abaddr); offset != nil {
+		return i.ram.Load32(*offset)
 	} else if offset := BIOS.Contains(addr); offset != nil {
 		return i.bios.Load32(*offset)
-	} else if offset := IRQ_CONTROL.Contains(addr); offset != nil {
-		fmt.Println("IRQ Control")
-		return 0
+	} else if offset := IRQ_CONTROL.Contains(abaddr); offset != nil {
+		return i.irq.Load(*offset)
+	} else if offset := TIMERS.Contains(abaddr); offset != nil {
+		return i.timers.Load(*offset)
 	} else if offset := DMA.Contains(abaddr); offset != nil {
 		return i.Dma_reg(*offset)
 	} else if offset := GPU.Contains(abaddr); offset != nil {
-		fmt.Println("GPU READ")
 		switch *offset {
-		case 4:
-			return 0x1c000000
+		case 0:
+			return i.gpu.Read()
 		default:
-			return 0
+			return i.gpu.Status()
 		}
+	} else if offset := SIO0.Contains(abaddr); offset != nil {
+		return i.sio0.Load(*offset)
+	} else if offset := SPU.Contains(abaddr); offset != nil { //Two 16 bit registers
+		return uint32(i.spu.Load16(*offset)) | uint32(i.spu.Load16(*offset+2))<<16
 	}
 
 	// Instead of panicking, log the unhandled address and return a default value
@@ -309,25 +410,29 @@ func (i *Interconnect) Load32(addr uint32) uint32 { //load 32-bit at addr
 	return 0 // Return a default value, or consider throwing an error if that's more appropriate
 }
 
-func (i *Interconnect) Load16(addr uint32) uint16 { //load 32-bit at addr
+func (i *Interconnect) load16(addr uint32) uint16 { //load 32-bit at addr
 	abaddr := Mask_region(addr)
 
 	if offset := SPU.Contains(abaddr); offset != nil {
-		fmt.Printf("Unhandled Write to Register: 0x%08x", abaddr)
-		return 0
+		return i.spu.Load16(*offset)
 	}
 	if offset := RAM.Contains(abaddr); offset != nil {
 		return i.ram.Load16(*offset)
 	}
-	if offset := IRQ_CONTROL.Contains(addr); offset != nil {
-		fmt.Println("IRQ Control")
-		return 0
+	if offset := IRQ_CONTROL.Contains(abaddr); offset != nil {
+		return uint16(i.irq.Load(*offset))
+	}
+	if offset := TIMERS.Contains(abaddr); offset != nil {
+		return uint16(i.timers.Load(*offset))
+	}
+	if offset := SIO0.Contains(abaddr); offset != nil {
+		return uint16(i.sio0.Load(*offset))
 	}
 	log.Printf("Unhandled Load16 at Address: 0x%08x", addr)
 	return 0
 }
 
-func (i *Interconnect) Load8(addr uint32) uint8 {
+func (i *Interconnect) load8(addr uint32) uint8 {
 	abaddr := Mask_region(addr)
 
 	if offset := RAM.Contains(abaddr); offset != nil {
@@ -338,6 +443,14 @@ func (i *Interconnect) Load8(addr uint32) uint8 {
 		return i.bios.Load8(*offset)
 	}
 
+	if offset := CDROM.Contains(abaddr); offset != nil {
+		return i.cdrom.Load8(*offset)
+	}
+
+	if offset := SIO0.Contains(abaddr); offset != nil {
+		return uint8(i.sio0.Load(*offset))
+	}
+
 	if offset := EX1.Contains(abaddr); offset == nil {
 		return 0xff
 	}
@@ -346,11 +459,14 @@ func (i *Interconnect) Load8(addr uint32) uint8 {
 	return 0
 }
 
-func (i *Interconnect) Store32(addr uint32, val uint32) { //Store value in address
+func (i *Interconnect) store32(addr uint32, val uint32) { //Store value in address
 	abaddr := Mask_region(addr)
 
 	if addr%4 != 0 {
 		panic(fmt.Sprintf("Unhandled Store32 address: 0x%08x", addr))
+	} else if offset := RAM.Contains(abaddr); offset != nil {
+		i.ram.Store32(*offset, val)
+		return
 	} else if offset := BIOS.Contains(addr); offset != nil {
 		switch *offset {
 		case 0: // Expansion 1 base address
@@ -365,8 +481,11 @@ func (i *Interconnect) Store32(addr uint32, val uint32) { //Store value in addre
 			fmt.Println("Unhandled write to MEM_CONTROL register")
 		}
 		return
-	} else if offset := IRQ_CONTROL.Contains(addr); offset != nil {
-		fmt.Println("IRQ Control")
+	} else if offset := IRQ_CONTROL.Contains(abaddr); offset != nil {
+		i.irq.Store(*offset, val)
+		return
+	} else if offset := TIMERS.Contains(abaddr); offset != nil {
+		i.timers.Store(*offset, val)
 		return
 	} else if offset := DMA.Contains(abaddr); offset != nil {
 		i.Set_dma_reg(*offset, val)
@@ -374,18 +493,24 @@ func (i *Interconnect) Store32(addr uint32, val uint32) { //Store value in addre
 	} else if offset := GPU.Contains(abaddr); offset != nil {
 		switch *offset {
 		case 0:
-			fmt.Println("GPU Write")
 			i.gpu.Gp0(val)
 		default:
-			fmt.Println("Unhandled GPU Write")
+			i.gpu.Gp1(val)
 		}
 		return
+	} else if offset := SIO0.Contains(abaddr); offset != nil {
+		i.sio0.Store(*offset, val)
+		return
+	} else if offset := SPU.Contains(abaddr); offset != nil {
+		i.spu.Store16(*offset, uint16(val))
+		i.spu.Store16(*offset+2, uint16(val>>16))
+		return
 	}
 
 	log.Printf("Unhandled store32 into address: 0x%08x 0x%08x", addr, val)
 }
 
-func (i *Interconnect) Store16(addr uint32, val uint16) {
+func (i *Interconnect) store16(addr uint32, val uint16) {
     if addr%2 != 0 {
         log.Printf("Error: Unaligned Store16 address: 0x%08x", addr)
         return // Consider handling this error more gracefully
@@ -394,30 +519,50 @@ func (i *Interconnect) Store16(addr uint32, val uint16) {
     abaddr := Mask_region(addr)
 
     if offset := SPU.Contains(abaddr); offset != nil {
-        // Implement or log unhandled write to SPU register
-        log.Printf("Warning: Unhandled Write to SPU Register: 0x%08x", val)
+        i.spu.Store16(*offset, val)
     } else if offset := TIMERS.Contains(abaddr); offset != nil {
-        // Implement or log unhandled write to Timer register
-        log.Printf("Warning: Unhandled Write to Timer Reg: 0x%x: 0x%08x", *offset, val)
+        i.timers.Store(*offset, uint32(val))
+    } else if offset := SIO0.Contains(abaddr); offset != nil {
+        i.sio0.Store(*offset, uint32(val))
     } else if offset := RAM.Contains(abaddr); offset != nil {
         i.ram.Store16(*offset, val)
-    } else if offset := IRQ_CONTROL.Contains(addr); offset != nil {
-        fmt.Println("IRQ Control Write")
+    } else if offset := IRQ_CONTROL.Contains(abaddr); offset != nil {
+        i.irq.Store(*offset, uint32(val))
     } else {
         // Log unhandled addresses instead of panicking
         log.Printf("Warning: Unhandled Store16 into address: 0x%08x", addr)
     }
 }
 
-func (i *Interconnect) Store8(addr uint32, val uint8) {
+func (i *Interconnect) store8(addr uint32, val uint8) {
 	abaddr := Mask_region(addr)
 
 	if offset := RAM.Contains(abaddr); offset != nil {
 		i.ram.Store8(*offset, val)
+		return
+	}
+
+	if offset := CDROM.Contains(abaddr); offset != nil {
+		i.cdrom.Store8(*offset, val)
+		return
+	}
+
+	if offset := SIO0.Contains(abaddr); offset != nil {
+		i.sio0.Store(*offset, uint32(val))
+		return
 	}
 
 	if offset := EX2.Contains(abaddr); offset != nil {
-		panic(fmt.Sprintf("Unhandled Write to EX2 Register: 0x%08x", val))
+		switch *offset {
+		case 0x23: //DUART channel A transmit
+			if i.tty != nil {
+				i.tty.Putchar(val)
+			}
+		case 0x41: //POST status LEDs
+		default:
+			log.Printf("Unhandled Write to EX2 Register 0x%x: 0x%02x", *offset, val)
+		}
+		return
 	}
 
 	log.Printf("Unhandled Store8 into address: 0x%08x", addr)
@@ -0,0 +1,94 @@
+package biosmap
+
+// Sees every CPU data access, for the debugger's watchpoints and the tracer
+type Watcher interface {
+	Load(addr uint32, size uint32, val uint32)
+	Store(addr uint32, size uint32, val uint32)
+}
+
+func (i *Interconnect) AddWatcher(w Watcher) {
+	i.watch = append(i.watch, w)
+}
+
+func (i *Interconnect) Fetch32(addr uint32) uint32 { //Instruction fetch, not reported to watchers
+	return i.load32(addr)
+}
+
+func (i *Interconnect) Load32(addr uint32) uint32 {
+	v := i.load32(addr)
+	for _, w := range i.watch {
+		w.Load(addr, 4, v)
+	}
+	return v
+}
+
+func (i *Interconnect) Load16(addr uint32) uint16 {
+	v := i.load16(addr)
+	for _, w := range i.watch {
+		w.Load(addr, 2, uint32(v))
+	}
+	return v
+}
+
+func (i *Interconnect) Load8(addr uint32) uint8 {
+	v := i.load8(addr)
+	for _, w := range i.watch {
+		w.Load(addr, 1, uint32(v))
+	}
+	return v
+}
+
+func (i *Interconnect) Store32(addr uint32, val uint32) {
+	for _, w := range i.watch {
+		w.Store(addr, 4, val)
+	}
+	i.store32(addr, val)
+}
+
+func (i *Interconnect) Store16(addr uint32, val uint16) {
+	for _, w := range i.watch {
+		w.Store(addr, 2, uint32(val))
+	}
+	i.store16(addr, val)
+}
+
+func (i *Interconnect) Store8(addr uint32, val uint8) {
+	for _, w := range i.watch {
+		w.Store(addr, 1, uint32(val))
+	}
+	i.store8(addr, val)
+}
+
+func (i *Interconnect) Peek8(addr uint32) (uint8, bool) { //Side-effect free read of RAM and BIOS
+	abaddr := Mask_region(addr)
+
+	if offset := RAM.Contains(abaddr); offset != nil {
+		return i.ram.Load8(*offset), true
+	}
+
+	if offset := BIOS.Contains(abaddr | 0xa0000000); offset != nil { //BIOS range is in KSEG1
+		return i.bios.Load8(*offset), true
+	}
+
+	return 0, false
+}
+
+func (i *Interconnect) Peek32(addr uint32) (uint32, bool) {
+	v := uint32(0)
+	for n := uint32(0); n < 4; n++ {
+		b, ok := i.Peek8(addr + n)
+		if !ok {
+			return 0, false
+		}
+		v |= uint32(b) << (8 * n)
+	}
+	return v, true
+}
+
+func (i *Interconnect) Poke8(addr uint32, val uint8) bool { //Side-effect free write, RAM only
+	if offset := RAM.Contains(Mask_region(addr)); offset != nil {
+		i.ram.Store8(*offset, val)
+		return true
+	}
+	return false
+}
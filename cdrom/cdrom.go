@@ -0,0 +1,505 @@
+package cdrom
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Koops0/GPSXE/irq"
+)
+
+// Delays in CPU cycles
+const (
+	CPU_CLOCK        uint32 = 33868800
+	INT3_DELAY       uint32 = 0xc4e1
+	GETID_DELAY      uint32 = 0x4a00
+	INIT_DELAY       uint32 = 0x13cce
+	IDLE_PAUSE_DELAY uint32 = 0x1df2
+	SEEK_MIN_DELAY   uint32 = 0x8000
+	SEEK_PER_SECTOR  uint32 = 100 //A full disc seek is about a second
+	SECTOR_DELAY_1X  uint32 = CPU_CLOCK / 75
+	FIFO_SIZE        int    = 16
+)
+
+// Interrupt types reported in the flag register
+const (
+	INT1 uint8 = 1 //Sector ready
+	INT2 uint8 = 2 //Second response
+	INT3 uint8 = 3 //First response
+	INT4 uint8 = 4 //End of data
+	INT5 uint8 = 5 //Error
+)
+
+// Status byte bits
+const (
+	StatError     uint8 = 0x01
+	StatMotorOn   uint8 = 0x02
+	StatSeekErr   uint8 = 0x04
+	StatIdErr     uint8 = 0x08
+	StatShellOpen uint8 = 0x10
+	StatReading   uint8 = 0x20
+	StatSeeking   uint8 = 0x40
+	StatPlaying   uint8 = 0x80
+)
+
+type DriveState uint8
+
+const (
+	Idle DriveState = iota
+	Seeking
+	Reading
+)
+
+// Queued interrupt, delivered once the previous one is acknowledged
+type Response struct {
+	delay  uint32
+	code   uint8
+	data   []uint8
+	sector []uint8 //Sector handed to the data buffer with an INT1
+}
+
+// CD-ROM controller at 0x1f801800
+type CdRom struct {
+	index       uint8
+	params      []uint8
+	response    []uint8
+	data        []uint8 //Data FIFO, loaded from the sector buffer
+	dataIndex   int
+	sector      []uint8 //Last sector read
+	irqEnable   uint8
+	irqFlags    uint8
+	queue       []Response
+	mode        uint8
+	state       DriveState
+	motorOn     bool
+	position    uint32 //Absolute sector under the head
+	setloc      uint32 //Target of the next seek or read
+	seekPending bool
+	readTimer   uint32
+	disc        *Disc
+	irq         *irq.InterruptState
+}
+
+func New(irq *irq.InterruptState) *CdRom {
+	return &CdRom{
+		params:   make([]uint8, 0, FIFO_SIZE),
+		response: make([]uint8, 0, FIFO_SIZE),
+		position: LEAD_IN_SECTORS,
+		setloc:   LEAD_IN_SECTORS,
+		irq:      irq,
+	}
+}
+
+func (c *CdRom) InsertDisc(disc *Disc) {
+	c.disc = disc
+	c.motorOn = disc != nil
+}
+
+func (c *CdRom) Disc() *Disc {
+	return c.disc
+}
+
+func (c *CdRom) Load8(offset uint32) uint8 {
+	switch offset {
+	case 0:
+		return c.Status()
+	case 1:
+		if len(c.response) == 0 {
+			return 0
+		}
+		v := c.response[0]
+		c.response = c.response[1:]
+		return v
+	case 2:
+		return c.readData()
+	case 3:
+		if c.index&1 == 0 {
+			return c.irqEnable | 0xe0
+		}
+		return c.irqFlags | 0xe0
+	default:
+		return 0
+	}
+}
+
+func (c *CdRom) Store8(offset uint32, val uint8) {
+	switch offset {
+	case 0:
+		c.index = val & 3
+	case 1:
+		switch c.index {
+		case 0:
+			c.Command(val)
+		default:
+			//Sound map and audio volume, no audio yet
+		}
+	case 2:
+		switch c.index {
+		case 0:
+			if len(c.params) < FIFO_SIZE {
+				c.params = append(c.params, val)
+			}
+		case 1:
+			c.irqEnable = val & 0x1f
+			c.updateIrq()
+		default:
+			//Audio volume
+		}
+	case 3:
+		switch c.index {
+		case 0:
+			c.setRequest(val)
+		case 1:
+			c.ack(val)
+		default:
+			//Audio volume
+		}
+	}
+}
+
+func (c *CdRom) Status() uint8 { //Index/status register
+	r := c.index
+	//Bit 2: XA-ADPCM fifo not empty, never
+	r |= boolToUint8(len(c.params) == 0) << 3
+	r |= boolToUint8(len(c.params) < FIFO_SIZE) << 4
+	r |= boolToUint8(len(c.response) > 0) << 5
+	r |= boolToUint8(c.dataIndex < len(c.data)) << 6
+	r |= boolToUint8(c.busy()) << 7
+	return r
+}
+
+func (c *CdRom) busy() bool { //Command accepted, first response not delivered yet
+	return len(c.queue) > 0 && c.queue[0].code == INT3
+}
+
+func (c *CdRom) setRequest(val uint8) {
+	if val&0x80 == 0 { //BFRD cleared, drop the data FIFO
+		c.data = nil
+		c.dataIndex = 0
+		return
+	}
+	if c.dataIndex < len(c.data) || c.sector == nil {
+		return
+	}
+
+	if c.mode&0x20 != 0 { //Whole sector minus sync
+		c.data = c.sector[12:]
+	} else { //Data only
+		c.data = c.sector[24 : 24+0x800]
+	}
+	c.dataIndex = 0
+}
+
+func (c *CdRom) ack(val uint8) {
+	c.irqFlags &^= val & 0x1f
+	if val&0x40 != 0 {
+		c.params = c.params[:0]
+	}
+	if c.irqFlags == 0 {
+		c.response = c.response[:0]
+	}
+}
+
+func (c *CdRom) readData() uint8 {
+	if c.dataIndex >= len(c.data) {
+		if len(c.data) == 0 {
+			return 0
+		}
+		return c.data[len(c.data)-1]
+	}
+	v := c.data[c.dataIndex]
+	c.dataIndex++
+	return v
+}
+
+func (c *CdRom) DmaRead() uint32 { //Channel 3, little endian words from the data FIFO
+	b0 := uint32(c.readData())
+	b1 := uint32(c.readData())
+	b2 := uint32(c.readData())
+	b3 := uint32(c.readData())
+	return b0 | (b1 << 8) | (b2 << 16) | (b3 << 24)
+}
+
+func (c *CdRom) Tick(cycles uint32) {
+	if c.state == Reading {
+		if c.readTimer > cycles {
+			c.readTimer -= cycles
+		} else {
+			c.readTimer = c.sectorDelay()
+			c.readSector()
+		}
+	}
+
+	if len(c.queue) == 0 || c.irqFlags != 0 {
+		return
+	}
+
+	head := &c.queue[0]
+	if head.delay > cycles {
+		head.delay -= cycles
+		return
+	}
+
+	r := *head
+	c.queue = c.queue[1:]
+	c.deliver(r)
+}
+
+func (c *CdRom) deliver(r Response) {
+	if r.sector != nil {
+		c.sector = r.sector
+	}
+	if r.code == INT2 && c.state == Seeking {
+		c.state = Idle
+	}
+
+	c.response = append(c.response[:0], r.data...)
+	c.irqFlags = r.code
+	c.updateIrq()
+}
+
+func (c *CdRom) updateIrq() {
+	if c.irqFlags&c.irqEnable != 0 {
+		c.irq.Assert(irq.CdRom)
+	}
+}
+
+func (c *CdRom) push(delay uint32, code uint8, data ...uint8) {
+	c.queue = append(c.queue, Response{delay: delay, code: code, data: data})
+}
+
+func (c *CdRom) stat() uint8 {
+	r := uint8(0)
+	if c.disc == nil {
+		r |= StatShellOpen
+	}
+	if c.motorOn {
+		r |= StatMotorOn
+	}
+	switch c.state {
+	case Seeking:
+		r |= StatSeeking
+	case Reading:
+		r |= StatReading
+	}
+	return r
+}
+
+func (c *CdRom) sectorDelay() uint32 {
+	if c.mode&0x80 != 0 { //Double speed
+		return SECTOR_DELAY_1X / 2
+	}
+	return SECTOR_DELAY_1X
+}
+
+func (c *CdRom) seekDelay() uint32 {
+	dist := c.setloc - c.position
+	if c.position > c.setloc {
+		dist = c.position - c.setloc
+	}
+	delay := SEEK_MIN_DELAY + dist*SEEK_PER_SECTOR
+	if delay > CPU_CLOCK {
+		delay = CPU_CLOCK
+	}
+	return delay
+}
+
+func (c *CdRom) Command(cmd uint8) {
+	params := c.params
+	c.params = make([]uint8, 0, FIFO_SIZE)
+
+	if len(c.queue) > 0 && c.queue[0].code != INT1 {
+		log.Printf("CD-ROM command 0x%02x while busy", cmd)
+	}
+
+	switch cmd {
+	case 0x01: //GetStat
+		c.push(INT3_DELAY, INT3, c.stat())
+	case 0x02: //Setloc
+		if len(params) < 3 {
+			c.error(0x20)
+			return
+		}
+		c.setloc = MsfFromBcd(params[0], params[1], params[2]).Sector()
+		c.seekPending = true
+		c.push(INT3_DELAY, INT3, c.stat())
+	case 0x06, 0x1b: //ReadN, ReadS
+		if c.disc == nil {
+			c.error(0x80)
+			return
+		}
+		c.push(INT3_DELAY, INT3, c.stat())
+		c.readTimer = c.sectorDelay()
+		if c.seekPending {
+			c.readTimer += c.seekDelay()
+			c.position = c.setloc
+			c.seekPending = false
+		}
+		c.motorOn = true
+		c.state = Reading
+	case 0x08: //Stop
+		c.push(INT3_DELAY, INT3, c.stat())
+		c.state = Idle
+		c.motorOn = false
+		c.push(INIT_DELAY, INT2, c.stat())
+	case 0x09: //Pause
+		delay := IDLE_PAUSE_DELAY
+		if c.state == Reading {
+			delay = c.sectorDelay() * 4
+		}
+		c.push(INT3_DELAY, INT3, c.stat())
+		c.state = Idle
+		c.dropSectors()
+		c.push(delay, INT2, c.stat())
+	case 0x0a: //Init
+		c.push(INT3_DELAY, INT3, c.stat())
+		c.mode = 0x20
+		c.state = Idle
+		c.motorOn = c.disc != nil
+		c.dropSectors()
+		c.push(INIT_DELAY, INT2, c.stat())
+	case 0x0b, 0x0c: //Mute, Demute
+		c.push(INT3_DELAY, INT3, c.stat())
+	case 0x0e: //Setmode
+		if len(params) < 1 {
+			c.error(0x20)
+			return
+		}
+		c.mode = params[0]
+		c.push(INT3_DELAY, INT3, c.stat())
+	case 0x13: //GetTN
+		if c.disc == nil {
+			c.error(0x80)
+			return
+		}
+		c.push(INT3_DELAY, INT3, c.stat(), ToBcd(c.disc.FirstTrack()), ToBcd(c.disc.LastTrack()))
+	case 0x14: //GetTD
+		if c.disc == nil || len(params) < 1 {
+			c.error(0x20)
+			return
+		}
+		c.getTD(FromBcd(params[0]))
+	case 0x15, 0x16: //SeekL, SeekP
+		if c.disc == nil {
+			c.error(0x80)
+			return
+		}
+		c.push(INT3_DELAY, INT3, c.stat())
+		c.state = Seeking
+		delay := c.seekDelay()
+		c.position = c.setloc
+		c.seekPending = false
+		c.push(delay, INT2, c.stat()&^StatSeeking)
+	case 0x19: //Test
+		if len(params) < 1 {
+			c.error(0x20)
+			return
+		}
+		c.test(params[0])
+	case 0x1a: //GetID
+		c.getID()
+	default:
+		log.Printf("Unhandled CD-ROM command: 0x%02x", cmd)
+		c.error(0x40)
+	}
+}
+
+func (c *CdRom) error(code uint8) { //INT5 with the error code
+	c.push(INT3_DELAY, INT5, c.stat()|StatError, code)
+}
+
+func (c *CdRom) dropSectors() { //Forget INT1s not delivered yet
+	queue := c.queue[:0]
+	for _, r := range c.queue {
+		if r.code != INT1 {
+			queue = append(queue, r)
+		}
+	}
+	c.queue = queue
+}
+
+func (c *CdRom) readSector() {
+	for _, r := range c.queue {
+		if r.code == INT1 { //Previous sector not taken yet, overrun
+			c.position++
+			return
+		}
+	}
+
+	sector, err := c.disc.ReadSector(c.position)
+	if err != nil {
+		log.Printf("CD-ROM read error at %s: %v", MsfFromSector(c.position), err)
+		c.push(0, INT5, c.stat()|StatError, 0x04)
+		c.state = Idle
+		return
+	}
+	c.position++
+
+	c.queue = append(c.queue, Response{code: INT1, data: []uint8{c.stat()}, sector: sector})
+}
+
+func (c *CdRom) getTD(track uint8) {
+	var msf Msf
+
+	if track == 0 { //Lead-out
+		msf = c.disc.LeadOut()
+	} else if t := c.disc.Track(track); t != nil {
+		msf = MsfFromSector(t.Start)
+	} else {
+		c.error(0x10)
+		return
+	}
+
+	m, s, _ := msf.Bcd()
+	c.push(INT3_DELAY, INT3, c.stat(), m, s)
+}
+
+func (c *CdRom) test(sub uint8) {
+	switch sub {
+	case 0x20: //Controller version, PU-7 from 1994/09/19
+		c.push(INT3_DELAY, INT3, 0x94, 0x09, 0x19, 0xc0)
+	default:
+		log.Printf("Unhandled CD-ROM test command: 0x%02x", sub)
+		c.error(0x10)
+	}
+}
+
+func (c *CdRom) getID() {
+	if c.disc == nil {
+		c.push(INT3_DELAY, INT5, 0x08, 0x40, 0, 0, 0, 0, 0, 0)
+		return
+	}
+
+	c.push(INT3_DELAY, INT3, c.stat())
+
+	if c.disc.Tracks[0].Type == Audio {
+		c.push(GETID_DELAY, INT5, c.stat()|StatIdErr, 0x90, 0, 0, 0, 0, 0, 0)
+		return
+	}
+
+	region := c.region()
+	c.push(GETID_DELAY, INT2, c.stat(), 0x00, 0x20, 0x00, region[0], region[1], region[2], region[3])
+}
+
+func (c *CdRom) region() string { //From the licence string in the system area
+	sector, err := c.disc.ReadSector(LEAD_IN_SECTORS + 4)
+	if err != nil {
+		return "SCEI"
+	}
+
+	licence := string(sector[24 : 24+0x50])
+	switch {
+	case strings.Contains(licence, "Amer"):
+		return "SCEA"
+	case strings.Contains(licence, "Euro"):
+		return "SCEE"
+	default:
+		return "SCEI"
+	}
+}
+
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,307 @@
+package cdrom
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type TrackType uint8
+
+const (
+	Audio TrackType = iota
+	Mode1
+	Mode2
+)
+
+type Track struct {
+	Number     uint8
+	Type       TrackType
+	Start      uint32 //Absolute sector of INDEX 01
+	Length     uint32 //Sectors until the next track's pregap
+	pregap     uint32 //Sectors before INDEX 01 that belong to this track
+	pregapFile bool   //Pregap is stored in the BIN (INDEX 00) rather than silent (PREGAP)
+	file       *os.File
+	fileOffset uint32 //Sector of INDEX 01 inside the file
+}
+
+// Disc image made of one or more BIN files
+type Disc struct {
+	Tracks []Track
+	files  []*os.File
+	end    uint32 //Lead-out, absolute sector
+}
+
+type cueTrack struct {
+	track  Track
+	index0 int64 //-1 when absent
+	index1 int64
+	pregap uint32
+}
+
+func Open(path string) (*Disc, error) { //Load a CUE sheet, or a bare BIN as a single data track
+	if strings.EqualFold(filepath.Ext(path), ".cue") {
+		return OpenCue(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sectors, err := fileSectors(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	d := &Disc{files: []*os.File{file}}
+	d.Tracks = []Track{{
+		Number: 1,
+		Type:   Mode2,
+		Start:  LEAD_IN_SECTORS,
+		Length: sectors,
+		file:   file,
+	}}
+	d.end = LEAD_IN_SECTORS + sectors
+	return d, nil
+}
+
+func OpenCue(path string) (*Disc, error) {
+	cue, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer cue.Close()
+
+	d := &Disc{}
+	dir := filepath.Dir(path)
+	base := LEAD_IN_SECTORS //Where sector 0 of the current file lands
+	silence := uint32(0)    //PREGAP sectors inserted in the current file
+	var file *os.File
+	var pending []cueTrack
+
+	flush := func() error { //Lay out the tracks of the current file
+		if file == nil {
+			return nil
+		}
+		sectors, err := fileSectors(file)
+		if err != nil {
+			return err
+		}
+		for _, ct := range pending {
+			if ct.index1 < 0 {
+				return fmt.Errorf("track %d has no INDEX 01", ct.track.Number)
+			}
+			t := ct.track
+			silence += ct.pregap
+			t.Start = base + silence + uint32(ct.index1)
+			t.fileOffset = uint32(ct.index1)
+			if ct.index0 >= 0 {
+				t.pregap = uint32(ct.index1 - ct.index0)
+				t.pregapFile = true
+			} else {
+				t.pregap = ct.pregap
+			}
+			d.Tracks = append(d.Tracks, t)
+		}
+		base += silence + sectors
+		silence = 0
+		pending = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(cue)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var cmdErr error
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if cmdErr = flush(); cmdErr != nil {
+				break
+			}
+			name, ok := quoted(text[len(fields[0]):])
+			if !ok {
+				cmdErr = errors.New("bad FILE entry")
+				break
+			}
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(dir, name)
+			}
+			file, cmdErr = os.Open(name)
+			if cmdErr == nil {
+				d.files = append(d.files, file)
+			}
+		case "TRACK":
+			if file == nil || len(fields) < 3 {
+				cmdErr = errors.New("TRACK outside of a FILE")
+				break
+			}
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 || n > 99 {
+				cmdErr = fmt.Errorf("bad track number %q", fields[1])
+				break
+			}
+			t := Track{Number: uint8(n), file: file}
+			switch strings.ToUpper(fields[2]) {
+			case "AUDIO":
+				t.Type = Audio
+			case "MODE1/2352":
+				t.Type = Mode1
+			case "MODE2/2352":
+				t.Type = Mode2
+			default:
+				cmdErr = fmt.Errorf("unsupported track type %s", fields[2])
+			}
+			pending = append(pending, cueTrack{track: t, index0: -1, index1: -1})
+		case "INDEX":
+			if len(pending) == 0 || len(fields) < 3 {
+				cmdErr = errors.New("INDEX outside of a TRACK")
+				break
+			}
+			msf, err := ParseMsf(fields[2])
+			if err != nil {
+				cmdErr = err
+				break
+			}
+			ct := &pending[len(pending)-1]
+			switch fields[1] {
+			case "00", "0":
+				ct.index0 = int64(msf.Sector())
+			case "01", "1":
+				ct.index1 = int64(msf.Sector())
+			}
+		case "PREGAP":
+			if len(pending) == 0 || len(fields) < 2 {
+				cmdErr = errors.New("PREGAP outside of a TRACK")
+				break
+			}
+			msf, err := ParseMsf(fields[1])
+			if err != nil {
+				cmdErr = err
+				break
+			}
+			pending[len(pending)-1].pregap = msf.Sector()
+		}
+
+		if cmdErr != nil {
+			d.Close()
+			return nil, fmt.Errorf("%s:%d: %w", path, line, cmdErr)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		d.Close()
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		d.Close()
+		return nil, err
+	}
+	if len(d.Tracks) == 0 {
+		d.Close()
+		return nil, fmt.Errorf("%s: no tracks", path)
+	}
+
+	d.end = base
+	for n := range d.Tracks {
+		if n+1 < len(d.Tracks) {
+			next := d.Tracks[n+1]
+			d.Tracks[n].Length = next.Start - next.pregap - d.Tracks[n].Start
+		} else {
+			d.Tracks[n].Length = d.end - d.Tracks[n].Start
+		}
+	}
+
+	return d, nil
+}
+
+func (d *Disc) Close() {
+	for _, f := range d.files {
+		f.Close()
+	}
+	d.files = nil
+}
+
+func (d *Disc) LeadOut() Msf {
+	return MsfFromSector(d.end)
+}
+
+func (d *Disc) FirstTrack() uint8 {
+	return d.Tracks[0].Number
+}
+
+func (d *Disc) LastTrack() uint8 {
+	return d.Tracks[len(d.Tracks)-1].Number
+}
+
+func (d *Disc) Track(number uint8) *Track {
+	for n := range d.Tracks {
+		if d.Tracks[n].Number == number {
+			return &d.Tracks[n]
+		}
+	}
+	return nil
+}
+
+func (d *Disc) ReadSector(sector uint32) ([]uint8, error) { //Raw 2352 byte sector
+	buf := make([]uint8, SECTOR_SIZE)
+
+	if sector >= d.end {
+		return buf, nil //Lead-out
+	}
+
+	for n := len(d.Tracks) - 1; n >= 0; n-- {
+		t := &d.Tracks[n]
+		if sector+t.pregap < t.Start {
+			continue
+		}
+		if sector < t.Start && !t.pregapFile {
+			return buf, nil //Silent pregap
+		}
+
+		offset := int64(t.fileOffset) + int64(sector) - int64(t.Start)
+		if _, err := t.file.ReadAt(buf, offset*int64(SECTOR_SIZE)); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	return buf, nil //Lead-in
+}
+
+func fileSectors(file *os.File) (uint32, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size()%int64(SECTOR_SIZE) != 0 {
+		return 0, fmt.Errorf("%s: size is not a multiple of %d", file.Name(), SECTOR_SIZE)
+	}
+	return uint32(info.Size() / int64(SECTOR_SIZE)), nil
+}
+
+func quoted(s string) (string, bool) { //FILE "name with spaces.bin" BINARY
+	start := strings.IndexByte(s, '"')
+	end := strings.LastIndexByte(s, '"')
+	if start < 0 || end <= start {
+		f := strings.Fields(s)
+		if len(f) == 0 {
+			return "", false
+		}
+		return f[0], true
+	}
+	return s[start+1 : end], true
+}
@@ -0,0 +1,61 @@
+package cdrom
+
+import (
+	"fmt"
+)
+
+const (
+	SECTOR_SIZE     uint32 = 2352
+	SECTORS_PER_SEC uint32 = 75
+	SECS_PER_MIN    uint32 = 60
+	LEAD_IN_SECTORS uint32 = 150 //Track 1 starts at 00:02:00
+)
+
+// Disc position as minutes, seconds and frames (sectors)
+type Msf struct {
+	M uint8
+	S uint8
+	F uint8
+}
+
+func MsfFromSector(sector uint32) Msf { //Absolute sector to MSF
+	f := sector % SECTORS_PER_SEC
+	s := (sector / SECTORS_PER_SEC) % SECS_PER_MIN
+	m := sector / (SECTORS_PER_SEC * SECS_PER_MIN)
+	return Msf{M: uint8(m), S: uint8(s), F: uint8(f)}
+}
+
+func MsfFromBcd(m, s, f uint8) Msf {
+	return Msf{M: FromBcd(m), S: FromBcd(s), F: FromBcd(f)}
+}
+
+func (m Msf) Sector() uint32 { //Absolute sector, 00:00:00 is 0
+	return (uint32(m.M)*SECS_PER_MIN+uint32(m.S))*SECTORS_PER_SEC + uint32(m.F)
+}
+
+func (m Msf) Bcd() (uint8, uint8, uint8) {
+	return ToBcd(m.M), ToBcd(m.S), ToBcd(m.F)
+}
+
+func (m Msf) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", m.M, m.S, m.F)
+}
+
+func FromBcd(b uint8) uint8 {
+	return (b>>4)*10 + (b & 0xf)
+}
+
+func ToBcd(v uint8) uint8 {
+	return (v/10)<<4 | (v % 10)
+}
+
+func ParseMsf(str string) (Msf, error) { //"mm:ss:ff" as found in CUE sheets
+	var m, s, f uint8
+	if _, err := fmt.Sscanf(str, "%d:%d:%d", &m, &s, &f); err != nil {
+		return Msf{}, fmt.Errorf("bad MSF %q: %w", str, err)
+	}
+	if s >= 60 || f >= 75 {
+		return Msf{}, fmt.Errorf("bad MSF %q", str)
+	}
+	return Msf{M: m, S: s, F: f}, nil
+}
@@ -3,6 +3,12 @@ package main
 import (
 	"fmt"
 	"github.com/Koops0/GPSXE/biosmap"
+	"github.com/Koops0/GPSXE/debugger"
+	"github.com/Koops0/GPSXE/exe"
+	"github.com/Koops0/GPSXE/gdbstub"
+	"github.com/Koops0/GPSXE/gte"
+	"github.com/Koops0/GPSXE/trace"
+	"github.com/Koops0/GPSXE/tty"
 )
 
 type RegIn uint32
@@ -32,11 +38,24 @@ type CPU struct {
 	epc        uint32 //Cop0 14
 	branch     bool   //if branch occured
 	delay_slot bool   //if inst executes
+	gte        *gte.GTE //Cop2
+	cycles     uint64   //Elapsed CPU cycles
+	sideload   *exe.Exe //Run once the BIOS reaches the shell
+	debugger   *debugger.Debugger
+	gdb        *gdbstub.Stub
+	tracer     *trace.Recorder
 }
 
+// Average cost of an instruction, drives the peripherals' clocks
+const CYCLES_PER_INST uint32 = 2
+
+// The BIOS jumps here once the kernel is set up, sideloaded EXEs start from there
+const SHELL_ENTRY uint32 = 0x80030000
+
 type Exception uint32
 
 const (
+	Interrupt          = 0x0
 	SysCall            = 0x8
 	Overflow           = 0xc
 	LoadAddressError   = 0x4
@@ -59,6 +78,7 @@ func (c *CPU) New(inter biosmap.Interconnect) CPU{
 	c.lo = 0xdeadbeef
 	c.branch = false
 	c.delay_slot = false
+	c.gte = gte.New()
 	return *c
 }
 
@@ -244,7 +264,7 @@ func (c *CPU) Opmtc0(inst Instruction) { //Or
 
 func (c *CPU) Oprfe(inst Instruction) { //Return from Exception
 	if inst.op&0x3f != 0b010000 {
-		panic(fmt.Sprintf("Invalid COP inst: %x", inst))
+		panic(fmt.Sprintf("Invalid COP inst: %s", inst.Disasm(c.current_pc)))
 	}
 
 	mode := c.sr & 0x3f //Shift interrupt
@@ -262,7 +282,7 @@ func (c *CPU) Opcop0(inst Instruction) {
 	case 0b10000:
 		c.Oprfe(inst)
 	default:
-		panic(fmt.Sprintf("Unhandled COP inst: %x", inst))
+		panic(fmt.Sprintf("Unhandled COP inst: %s", inst.Disasm(c.current_pc)))
 	}
 }
 
@@ -271,7 +291,58 @@ func (c *CPU) Opcop1(Instruction) {
 }
 
 func (c *CPU) Opcop2(inst Instruction) {
-	panic(fmt.Sprintf("Unhandled inst: %x", inst))
+	if c.sr&(1<<30) == 0 { //Cop2 disabled
+		c.Exception(CoprocessorError)
+		return
+	}
+
+	if inst.S()&0x10 != 0 { //GTE command
+		c.gte.Command(inst.op)
+		return
+	}
+
+	switch inst.S() {
+	case 0b00000:
+		c.Opmfc2(inst)
+	case 0b00010:
+		c.Opcfc2(inst)
+	case 0b00100:
+		c.Opmtc2(inst)
+	case 0b00110:
+		c.Opctc2(inst)
+	default:
+		panic(fmt.Sprintf("Unhandled GTE inst: %s", inst.Disasm(c.current_pc)))
+	}
+}
+
+func (c *CPU) Opmfc2(inst Instruction) { //Move from GTE data
+	cpu_r := inst.T()
+	cop_r := inst.D()
+
+	v := c.gte.Data(cop_r)
+	c.load.Load(RegIn(cpu_r), v)
+}
+
+func (c *CPU) Opcfc2(inst Instruction) { //Move from GTE control
+	cpu_r := inst.T()
+	cop_r := inst.D()
+
+	v := c.gte.Control(cop_r)
+	c.load.Load(RegIn(cpu_r), v)
+}
+
+func (c *CPU) Opmtc2(inst Instruction) { //Move to GTE data
+	cpu_r := inst.T()
+	cop_r := inst.D()
+
+	c.gte.SetData(cop_r, c.reg[cpu_r])
+}
+
+func (c *CPU) Opctc2(inst Instruction) { //Move to GTE control
+	cpu_r := inst.T()
+	cop_r := inst.D()
+
+	c.gte.SetControl(cop_r, c.reg[cpu_r])
 }
 
 func (c *CPU) Opcop3(Instruction) {
@@ -895,8 +966,19 @@ func (c *CPU) Oplwc1(Instruction) {
 	c.Exception(CoprocessorError)
 }
 
-func (c *CPU) Oplwc2(inst Instruction) {
-	panic(fmt.Sprintf("Unhandled inst: %x", inst))
+func (c *CPU) Oplwc2(inst Instruction) { //Load word to GTE
+	i := inst.Imm_se()
+	t := inst.T()
+	s := inst.S()
+
+	addr := c.reg[s] + i
+
+	if addr%4 == 0 {
+		v := c.Load32(addr)
+		c.gte.SetData(t, v)
+	} else {
+		c.Exception(LoadAddressError)
+	}
 }
 
 func (c *CPU) Oplwc3(Instruction) {
@@ -911,8 +993,19 @@ func (c *CPU) Opswc1(Instruction) {
 	c.Exception(CoprocessorError)
 }
 
-func (c *CPU) Opswc2(inst Instruction) {
-	panic(fmt.Sprintf("Unhandled inst: %x", inst))
+func (c *CPU) Opswc2(inst Instruction) { //Store word from GTE
+	i := inst.Imm_se()
+	t := inst.T()
+	s := inst.S()
+
+	addr := c.reg[s] + i
+	v := c.gte.Data(t)
+
+	if addr%4 == 0 {
+		c.Store32(addr, v)
+	} else {
+		c.Exception(StoreAddressError)
+	}
 }
 
 func (c *CPU) Opswc3(Instruction) {
@@ -928,7 +1021,7 @@ func (c *CPU) Opsyscall(Instruction) { //Syscall
 }
 
 func (c *CPU) Opillegal(inst Instruction) { //Syscall
-	fmt.Printf("Illegal instruction: %x", inst)
+	fmt.Printf("Illegal instruction: %s\n", inst.Disasm(c.current_pc))
 	c.Exception(IllegalInstruction)
 }
 
@@ -1099,14 +1192,47 @@ func (c *CPU) Decode_and_execute(inst Instruction) {
 }
 
 func (c *CPU) Run_next() {
+	if c.debugger != nil {
+		c.debugger.Before(c)
+	}
+	if c.gdb != nil {
+		c.gdb.Before(c)
+	}
+	if c.tracer != nil {
+		c.tracer.Begin(c)
+		defer c.tracer.End(c)
+	}
+
+	c.cycles += uint64(CYCLES_PER_INST)
+	c.inter.Tick(CYCLES_PER_INST)
+
+	if c.sideload != nil && c.pc == SHELL_ENTRY {
+		c.Load_exe(c.sideload)
+		c.sideload = nil
+	}
+
 	c.current_pc = c.pc
+	c.delay_slot = c.branch //Exception needs to know if this instruction is in a delay slot
+	c.branch = false
 
 	if c.current_pc % 4 != 0 {
 		c.Exception(LoadAddressError)
 		return
 	}
 
-	inst := Instruction{op: c.Load32(c.pc)}
+	inst := Instruction{op: c.inter.Fetch32(c.pc)}
+
+	if c.Irq_pending() {
+		// GTE commands still run, the BIOS handler skips them on return
+		if inst.Function() == 0b010010 && inst.S()&0x10 != 0 {
+			c.gte.Command(inst.op)
+		}
+		if c.tracer != nil {
+			c.tracer.Drop()
+		}
+		c.Exception(Interrupt)
+		return
+	}
 
 	c.pc = c.next_pc
 	c.next_pc = Wrapping_add(c.next_pc, 4, 32)
@@ -1114,13 +1240,138 @@ func (c *CPU) Run_next() {
 	c.Set_reg(c.load.r, c.load.val)
 	c.load.Load(0,0)
 
-	c.delay_slot = c.branch
-	c.branch = false
+	if t := c.inter.TTY(); t != nil { //Only once the call really runs, not when an IRQ pre-empts it
+		c.Capture_putchar(t)
+	}
+
 	c.Decode_and_execute(inst) // Cast inst to Instruction type
 
 	c.reg = c.out_reg
 }
 
+func (c *CPU) Attach_debugger(d *debugger.Debugger) { //Checked before every instruction
+	c.debugger = d
+	c.inter.AddWatcher(d)
+}
+
+func (c *CPU) Attach_gdb(s *gdbstub.Stub) {
+	c.gdb = s
+}
+
+func (c *CPU) Attach_tracer(r *trace.Recorder) { //Record every step
+	c.tracer = r
+	c.inter.AddWatcher(r)
+}
+
+func (c *CPU) PC() uint32 {
+	return c.pc
+}
+
+func (c *CPU) SetPC(val uint32) { //Jump there, dropping any pending branch
+	c.pc = val
+	c.next_pc = val + 4
+	c.branch = false
+	c.delay_slot = false
+}
+
+func (c *CPU) SetRegister(index uint32, val uint32) { //Between instructions reg and out_reg agree
+	if index == 0 {
+		return
+	}
+	c.reg[index] = val
+	c.out_reg[index] = val
+}
+
+func (c *CPU) SetHi(val uint32) {
+	c.hi = val
+}
+
+func (c *CPU) SetLo(val uint32) {
+	c.lo = val
+}
+
+func (c *CPU) Hi() uint32 {
+	return c.hi
+}
+
+func (c *CPU) Lo() uint32 {
+	return c.lo
+}
+
+func (c *CPU) Cop0(index uint32) uint32 { //Only the registers we model
+	switch index {
+	case 12:
+		return c.sr
+	case 13:
+		return c.cause
+	case 14:
+		return c.epc
+	default:
+		return 0
+	}
+}
+
+func (c *CPU) Cycles() uint64 {
+	return c.cycles
+}
+
+func (c *CPU) Peek8(addr uint32) (uint8, bool) {
+	return c.inter.Peek8(addr)
+}
+
+func (c *CPU) Peek32(addr uint32) (uint32, bool) {
+	return c.inter.Peek32(addr)
+}
+
+func (c *CPU) Poke8(addr uint32, val uint8) bool {
+	return c.inter.Poke8(addr, val)
+}
+
+func (c *CPU) Sideload(e *exe.Exe) { //Boot the BIOS, then jump into the EXE instead of the shell
+	c.sideload = e
+}
+
+func (c *CPU) Load_exe(e *exe.Exe) { //Copy the payload to RAM and set up the registers
+	ram := c.inter.Ram()
+	ram.Write(e.Text&0x1fffff, e.Payload)
+	if e.BssSize != 0 {
+		ram.Write(e.Bss&0x1fffff, make([]uint8, e.BssSize))
+	}
+
+	c.reg[28] = e.GP
+	if sp, ok := e.StackPointer(); ok {
+		c.reg[29] = sp
+		c.reg[30] = sp
+	}
+	c.out_reg = c.reg
+	c.load.Load(0, 0)
+
+	c.pc = e.PC
+	c.next_pc = e.PC + 4
+	c.branch = false
+	c.delay_slot = false
+}
+
+func (c *CPU) Capture_putchar(t *tty.TTY) { //BIOS A(0x3C) and B(0x3D), char in $a0
+	pc := c.current_pc & 0x1fffffff
+	fn := c.reg[9]
+
+	if (pc == 0xa0 && fn == 0x3c) || (pc == 0xb0 && fn == 0x3d) {
+		t.Putchar(uint8(c.reg[4]))
+	}
+}
+
+func (c *CPU) Irq_pending() bool { //Cause.IP2 follows the interrupt controller
+	if c.inter.Irq().Active() {
+		c.cause |= 1 << 10
+	} else {
+		c.cause &^= 1 << 10
+	}
+
+	pending := c.cause & c.sr & 0xff00 //IM bits
+	return c.sr&1 != 0 && pending != 0 //IEc
+}
+
 func (c *CPU) Load32(addr uint32) uint32 { //load 32-bit from inter
 	return c.inter.Load32(addr)
 }
@@ -1138,7 +1389,7 @@ func (c *CPU) Exception(cause Exception) { //Trigger Exception
 	c.sr &= ^uint32(0x3f)
 	c.sr |= (mode << 2) & 0x3f
 
-	c.cause = uint32(cause) << 2 //Update cause
+	c.cause = (c.cause & 0xff00) | uint32(cause) << 2 //Update cause, keep pending IP bits
 
 	c.epc = c.current_pc
 
@@ -1147,5 +1398,5 @@ func (c *CPU) Exception(cause Exception) { //Trigger Exception
 		c.cause |= 1 << 31
 	}
 	c.pc = handler
-	c.next_pc = Wrapping_add(c.next_pc, 4, 32)
+	c.next_pc = handler + 4
 }
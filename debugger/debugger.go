@@ -0,0 +1,200 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/Koops0/GPSXE/disasm"
+)
+
+// What the debugger needs to see of the CPU
+type Target interface {
+	PC() uint32
+	Reg(index uint32) uint32
+	Hi() uint32
+	Lo() uint32
+	Cop0(index uint32) uint32
+	Cycles() uint64
+	Peek8(addr uint32) (uint8, bool)
+	Peek32(addr uint32) (uint32, bool)
+}
+
+type Mode int
+
+const (
+	Running  Mode = iota
+	Stepping      //Stop after a number of instructions
+	Until         //Stop when the PC reaches a target, step-over
+	Out           //Stop once the current function returns
+)
+
+type Access uint32
+
+const (
+	Read  Access = 1 << 0
+	Write Access = 1 << 1
+)
+
+type Watchpoint struct {
+	Start  uint32 //Physical address, inclusive
+	End    uint32 //Physical address, exclusive
+	Access Access
+	Value  *uint32 //Only trigger when this value is read or written
+}
+
+type Debugger struct {
+	in  *bufio.Scanner
+	out io.Writer
+
+	breakpoints []uint32
+	watchpoints []Watchpoint
+	hit         string //Watchpoint report, shown before the next instruction
+
+	mode   Mode
+	steps  int
+	target uint32 //Until: PC to stop at
+	sp     uint32 //Until: stack pointer must be back at this level
+	depth  int    //Out: calls entered since step-out
+	delay  bool   //The next instruction sits in a branch delay slot
+	last   string //Repeated on an empty line
+	quit   bool
+}
+
+func New(in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		in:   bufio.NewScanner(in),
+		out:  out,
+		mode: Stepping, //Stop on the reset vector
+	}
+}
+
+func (d *Debugger) Quitting() bool {
+	return d.quit
+}
+
+// Called before every instruction
+func (d *Debugger) Before(t Target) {
+	if d.quit {
+		return
+	}
+
+	if reason := d.stopReason(t); reason != "" {
+		d.mode = Running
+		d.repl(t, reason)
+		if d.quit {
+			return
+		}
+	}
+
+	word, _ := t.Peek32(t.PC())
+	d.account(t, word)
+	d.delay = disasm.HasDelaySlot(word)
+}
+
+func (d *Debugger) stopReason(t Target) string {
+	pc := t.PC()
+
+	if d.hit != "" {
+		hit := d.hit
+		d.hit = ""
+		return hit
+	}
+
+	if d.hasBreakpoint(pc) {
+		return fmt.Sprintf("Breakpoint at 0x%08x", pc)
+	}
+
+	if d.delay { //A branch and its delay slot count as one step
+		return ""
+	}
+
+	switch d.mode {
+	case Stepping:
+		if d.steps <= 0 {
+			return "Step"
+		}
+	case Until:
+		if pc == d.target && t.Reg(29) >= d.sp {
+			return "Step"
+		}
+	}
+	return ""
+}
+
+func (d *Debugger) account(t Target, word uint32) { //Bookkeeping for the instruction about to run
+	if d.delay {
+		return
+	}
+
+	switch d.mode {
+	case Stepping:
+		d.steps--
+	case Out:
+		if disasm.IsCall(word) {
+			d.depth++
+		} else if disasm.IsReturn(word) {
+			if d.depth == 0 {
+				d.mode = Until
+				d.target = t.Reg(31)
+				d.sp = 0
+			} else {
+				d.depth--
+			}
+		}
+	}
+}
+
+// Called when the emulator panics, the process lives on in the REPL
+func (d *Debugger) Panic(t Target, err interface{}, stack []byte) {
+	fmt.Fprintf(d.out, "%s\n", stack)
+	d.mode = Running
+	d.repl(t, fmt.Sprintf("Panic: %v", err))
+}
+
+func (d *Debugger) hasBreakpoint(pc uint32) bool {
+	for _, b := range d.breakpoints {
+		if b == pc {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) Load(addr uint32, size uint32, val uint32) { //biosmap.Watcher
+	d.check(addr, size, val, Read)
+}
+
+func (d *Debugger) Store(addr uint32, size uint32, val uint32) { //biosmap.Watcher
+	d.check(addr, size, val, Write)
+}
+
+func (d *Debugger) check(addr uint32, size uint32, val uint32, access Access) {
+	if d.hit != "" {
+		return
+	}
+
+	phys := physical(addr)
+	for n, w := range d.watchpoints {
+		if w.Access&access == 0 || phys+size <= w.Start || phys >= w.End {
+			continue
+		}
+		if w.Value != nil && *w.Value != val {
+			continue
+		}
+
+		verb := "read"
+		if access == Write {
+			verb = "write"
+		}
+		d.hit = fmt.Sprintf("Watchpoint %d: %s%d 0x%08x = 0x%x", n, verb, size*8, addr, val)
+		return
+	}
+}
+
+func physical(addr uint32) uint32 { //Fold KUSEG/KSEG0/KSEG1 mirrors, KSEG2 is left alone
+	if addr >= 0xc0000000 {
+		return addr
+	}
+	return addr & 0x1fffffff
+}
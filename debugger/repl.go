@@ -0,0 +1,334 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Koops0/GPSXE/disasm"
+)
+
+const HELP = `Numbers and addresses are hex, registers can be used as $name or pc
+  c, continue            run until a breakpoint or watchpoint
+  s, step [n]            run n instructions, a branch and its delay slot are one
+  n, next                step over calls
+  finish                 run until the current function returns
+  b, break <addr>        add a PC breakpoint
+  w, watch <addr> [len] [r|w|rw] [=value]
+                         stop on accesses to [addr, addr+len)
+  d, delete <n|all>      remove a breakpoint, wN for a watchpoint
+  i, info                list breakpoints and watchpoints
+  r, regs                general purpose registers
+  cop0                   SR, CAUSE and EPC
+  x <addr> [len]         hex dump
+  l, list [addr] [n]     disassemble n instructions, from pc by default
+  q, quit                exit the emulator
+An empty line repeats the last command`
+
+func (d *Debugger) repl(t Target, reason string) {
+	d.where(t, reason)
+
+	for {
+		fmt.Fprint(d.out, "(psx) ")
+		if !d.in.Scan() {
+			d.quit = true
+			return
+		}
+
+		line := strings.TrimSpace(d.in.Text())
+		if line == "" {
+			line = d.last
+		}
+		d.last = line
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		if resume, err := d.command(t, args[0], args[1:]); err != nil {
+			fmt.Fprintln(d.out, "Error:", err)
+		} else if resume {
+			return
+		}
+	}
+}
+
+func (d *Debugger) command(t Target, cmd string, args []string) (bool, error) { //True resumes execution
+	switch cmd {
+	case "h", "help":
+		fmt.Fprintln(d.out, HELP)
+	case "c", "continue":
+		d.mode = Running
+		return true, nil
+	case "s", "step":
+		n := uint32(1)
+		if len(args) > 0 {
+			v, err := d.value(t, args[0])
+			if err != nil {
+				return false, err
+			}
+			n = v
+		}
+		d.mode = Stepping
+		d.steps = int(n)
+		return true, nil
+	case "n", "next":
+		pc := t.PC()
+		word, _ := t.Peek32(pc)
+		if disasm.IsCall(word) && !d.delay {
+			d.mode = Until
+			d.target = pc + 8 //Past the delay slot
+			d.sp = t.Reg(29)
+		} else {
+			d.mode = Stepping
+			d.steps = 1
+		}
+		return true, nil
+	case "finish":
+		d.mode = Out
+		d.depth = 0
+		return true, nil
+	case "b", "break":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: break <addr>")
+		}
+		addr, err := d.value(t, args[0])
+		if err != nil {
+			return false, err
+		}
+		d.breakpoints = append(d.breakpoints, addr)
+		fmt.Fprintf(d.out, "Breakpoint %d at 0x%08x\n", len(d.breakpoints)-1, addr)
+	case "w", "watch":
+		return false, d.watch(t, args)
+	case "d", "delete":
+		return false, d.delete(args)
+	case "i", "info":
+		d.info()
+	case "r", "regs":
+		d.regs(t)
+	case "cop0":
+		d.cop0(t)
+	case "x":
+		return false, d.dump(t, args)
+	case "l", "list":
+		return false, d.list(t, args)
+	case "q", "quit":
+		d.quit = true
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q, try help", cmd)
+	}
+	return false, nil
+}
+
+func (d *Debugger) where(t Target, reason string) {
+	pc := t.PC()
+	fmt.Fprintf(d.out, "%s\n", reason)
+
+	slot := ""
+	if d.delay {
+		slot = " (delay slot)"
+	}
+	if word, ok := t.Peek32(pc); ok {
+		fmt.Fprintf(d.out, "0x%08x: %08x  %s%s\n", pc, word, disasm.Disassemble(pc, word), slot)
+	} else {
+		fmt.Fprintf(d.out, "0x%08x: ????????%s\n", pc, slot)
+	}
+}
+
+func (d *Debugger) value(t Target, arg string) (uint32, error) { //Hex number or register
+	name := strings.TrimPrefix(arg, "$")
+	if name == "pc" {
+		return t.PC(), nil
+	}
+	for i, r := range disasm.REG_NAMES {
+		if name == r || name == fmt.Sprintf("r%d", i) {
+			return t.Reg(uint32(i)), nil
+		}
+	}
+
+	v, err := strconv.ParseUint(strings.TrimPrefix(arg, "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", arg)
+	}
+	return uint32(v), nil
+}
+
+func (d *Debugger) watch(t Target, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: watch <addr> [len] [r|w|rw] [=value]")
+	}
+
+	addr, err := d.value(t, args[0])
+	if err != nil {
+		return err
+	}
+	w := Watchpoint{Start: physical(addr), Access: Write}
+	size := uint32(4)
+
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "r":
+			w.Access = Read
+		case arg == "w":
+			w.Access = Write
+		case arg == "rw":
+			w.Access = Read | Write
+		case strings.HasPrefix(arg, "="):
+			v, err := d.value(t, arg[1:])
+			if err != nil {
+				return err
+			}
+			w.Value = &v
+		default:
+			if size, err = d.value(t, arg); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.End = w.Start + size
+	d.watchpoints = append(d.watchpoints, w)
+	fmt.Fprintf(d.out, "Watchpoint %d at 0x%08x-0x%08x\n", len(d.watchpoints)-1, w.Start, w.End)
+	return nil
+}
+
+func (d *Debugger) delete(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: delete <n|wN|all>")
+	}
+
+	if args[0] == "all" {
+		d.breakpoints = nil
+		d.watchpoints = nil
+		return nil
+	}
+
+	if strings.HasPrefix(args[0], "w") {
+		n, err := strconv.Atoi(args[0][1:])
+		if err != nil || n < 0 || n >= len(d.watchpoints) {
+			return fmt.Errorf("no watchpoint %s", args[0])
+		}
+		d.watchpoints = append(d.watchpoints[:n], d.watchpoints[n+1:]...)
+		return nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 || n >= len(d.breakpoints) {
+		return fmt.Errorf("no breakpoint %s", args[0])
+	}
+	d.breakpoints = append(d.breakpoints[:n], d.breakpoints[n+1:]...)
+	return nil
+}
+
+func (d *Debugger) info() {
+	for n, b := range d.breakpoints {
+		fmt.Fprintf(d.out, "b%-3d 0x%08x\n", n, b)
+	}
+	for n, w := range d.watchpoints {
+		access := map[Access]string{Read: "r", Write: "w", Read | Write: "rw"}[w.Access]
+		fmt.Fprintf(d.out, "w%-3d 0x%08x-0x%08x %-2s", n, w.Start, w.End, access)
+		if w.Value != nil {
+			fmt.Fprintf(d.out, " =0x%x", *w.Value)
+		}
+		fmt.Fprintln(d.out)
+	}
+}
+
+func (d *Debugger) regs(t Target) {
+	for i := uint32(0); i < 32; i++ {
+		fmt.Fprintf(d.out, "%4s=%08x", disasm.REG_NAMES[i], t.Reg(i))
+		if i%4 == 3 {
+			fmt.Fprintln(d.out)
+		} else {
+			fmt.Fprint(d.out, " ")
+		}
+	}
+	fmt.Fprintf(d.out, "  pc=%08x   hi=%08x   lo=%08x cycles=%d\n", t.PC(), t.Hi(), t.Lo(), t.Cycles())
+}
+
+func (d *Debugger) cop0(t Target) {
+	sr := t.Cop0(12)
+	cause := t.Cop0(13)
+	fmt.Fprintf(d.out, "   sr=%08x IEc=%d KUc=%d IM=%02x BEV=%d IsC=%d\n",
+		sr, sr&1, (sr>>1)&1, (sr>>8)&0xff, (sr>>22)&1, (sr>>16)&1)
+	fmt.Fprintf(d.out, "cause=%08x ExcCode=%d IP=%02x BD=%d\n",
+		cause, (cause>>2)&0x1f, (cause>>8)&0xff, cause>>31)
+	fmt.Fprintf(d.out, "  epc=%08x\n", t.Cop0(14))
+}
+
+func (d *Debugger) dump(t Target, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("usage: x <addr> [len]")
+	}
+
+	addr, err := d.value(t, args[0])
+	if err != nil {
+		return err
+	}
+	size := uint32(0x40)
+	if len(args) == 2 {
+		if size, err = d.value(t, args[1]); err != nil {
+			return err
+		}
+	}
+
+	for row := addr &^ 0xf; row < addr+size; row += 16 {
+		var hex, text strings.Builder
+		for n := uint32(0); n < 16; n++ {
+			b, ok := t.Peek8(row + n)
+			switch {
+			case row+n < addr || row+n >= addr+size:
+				hex.WriteString("   ")
+				text.WriteByte(' ')
+			case !ok:
+				hex.WriteString("?? ")
+				text.WriteByte('?')
+			default:
+				fmt.Fprintf(&hex, "%02x ", b)
+				if b >= 0x20 && b < 0x7f {
+					text.WriteByte(b)
+				} else {
+					text.WriteByte('.')
+				}
+			}
+		}
+		fmt.Fprintf(d.out, "%08x  %s |%s|\n", row, hex.String(), text.String())
+	}
+	return nil
+}
+
+func (d *Debugger) list(t Target, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: list [addr] [n]")
+	}
+
+	addr := t.PC()
+	n := uint32(8)
+	var err error
+	if len(args) > 0 {
+		if addr, err = d.value(t, args[0]); err != nil {
+			return err
+		}
+	}
+	if len(args) > 1 {
+		if n, err = d.value(t, args[1]); err != nil {
+			return err
+		}
+	}
+
+	for addr &^= 3; n > 0; n-- {
+		marker := "  "
+		if addr == t.PC() {
+			marker = "=>"
+		}
+		if word, ok := t.Peek32(addr); ok {
+			fmt.Fprintf(d.out, "%s 0x%08x: %08x  %s\n", marker, addr, word, disasm.Disassemble(addr, word))
+		} else {
+			fmt.Fprintf(d.out, "%s 0x%08x: ????????\n", marker, addr)
+		}
+		addr += 4
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package disasm
+
+// Control flow classification, used for stepping and tracing
+
+func HasDelaySlot(word uint32) bool {
+	switch op(word) {
+	case 0x00:
+		return funct(word) == 0x08 || funct(word) == 0x09 //JR, JALR
+	case 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07: //BcondZ, J, JAL, BEQ, BNE, BLEZ, BGTZ
+		return true
+	}
+	return false
+}
+
+func IsCall(word uint32) bool { //Writes a return address
+	switch op(word) {
+	case 0x00:
+		return funct(word) == 0x09 //JALR
+	case 0x01:
+		return rt(word)&0x1e == 0x10 //BLTZAL, BGEZAL
+	case 0x03: //JAL
+		return true
+	}
+	return false
+}
+
+func IsReturn(word uint32) bool { //JR $ra
+	return op(word) == 0x00 && funct(word) == 0x08 && rs(word) == 31
+}
+
+// Where a branch or J/JAL goes if taken, false for register jumps and everything else
+func BranchTarget(pc uint32, word uint32) (uint32, bool) {
+	switch op(word) {
+	case 0x01, 0x04, 0x05, 0x06, 0x07:
+		return pc + 4 + uint32(simm(word)<<2), true
+	case 0x02, 0x03:
+		return (pc+4)&0xf0000000 | (word&0x3ffffff)<<2, true
+	}
+	return 0, false
+}
@@ -0,0 +1,82 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+)
+
+var gteNames = map[uint32]string{
+	0x01: "rtps", 0x06: "nclip", 0x0c: "op", 0x10: "dpcs", 0x11: "intpl",
+	0x12: "mvmva", 0x13: "ncds", 0x14: "cdp", 0x16: "ncdt", 0x1b: "nccs",
+	0x1c: "cc", 0x1e: "ncs", 0x20: "nct", 0x28: "sqr", 0x29: "dcpl",
+	0x2a: "dpct", 0x2d: "avsz3", 0x2e: "avsz4", 0x30: "rtpt", 0x3d: "gpf",
+	0x3e: "gpl", 0x3f: "ncct",
+}
+
+var cop0Names = map[uint32]string{
+	3: "bpc", 5: "bda", 6: "jumpdest", 7: "dcic", 8: "badvaddr",
+	9: "bdam", 11: "bpcm", 12: "sr", 13: "cause", 14: "epc", 15: "prid",
+}
+
+func cop(word uint32) string {
+	z := op(word) & 3
+
+	if rs(word)&0x10 != 0 { //Coprocessor command
+		switch z {
+		case 0:
+			if funct(word) == 0x10 {
+				return "rfe"
+			}
+		case 2:
+			return gte(word)
+		}
+		return fmt.Sprintf("cop%d 0x%07x", z, word&0x1ffffff)
+	}
+
+	switch rs(word) {
+	case 0x00:
+		return fmt.Sprintf("mfc%d %s, %s", z, reg(rt(word)), copReg(z, rd(word)))
+	case 0x02:
+		return fmt.Sprintf("cfc%d %s, $%d", z, reg(rt(word)), rd(word))
+	case 0x04:
+		return fmt.Sprintf("mtc%d %s, %s", z, reg(rt(word)), copReg(z, rd(word)))
+	case 0x06:
+		return fmt.Sprintf("ctc%d %s, $%d", z, reg(rt(word)), rd(word))
+	case 0x08:
+		return illegal(word) //BCzF/BCzT, nothing on the PSX drives the condition
+	}
+	return illegal(word)
+}
+
+func copReg(z uint32, r uint32) string {
+	if name, ok := cop0Names[r]; ok && z == 0 {
+		return "$" + name
+	}
+	return fmt.Sprintf("$%d", r)
+}
+
+func gte(word uint32) string { //Name plus the sf/lm flags, MVMVA also gets its operands
+	name, ok := gteNames[funct(word)]
+	if !ok {
+		return fmt.Sprintf("cop2 0x%07x", word&0x1ffffff)
+	}
+
+	var args []string
+	if funct(word) == 0x12 {
+		args = append(args,
+			[4]string{"rt", "llm", "lcm", "bad"}[(word>>17)&3],
+			[4]string{"v0", "v1", "v2", "ir"}[(word>>15)&3],
+			[4]string{"tr", "bk", "fc", "none"}[(word>>13)&3])
+	}
+	if word&(1<<19) != 0 {
+		args = append(args, "sf")
+	}
+	if word&(1<<10) != 0 {
+		args = append(args, "lm")
+	}
+
+	if len(args) == 0 {
+		return name
+	}
+	return name + " " + strings.Join(args, ", ")
+}
@@ -0,0 +1,192 @@
+package disasm
+
+import "fmt"
+
+var REG_NAMES = [32]string{
+	"zero", "at", "v0", "v1", "a0", "a1", "a2", "a3",
+	"t0", "t1", "t2", "t3", "t4", "t5", "t6", "t7",
+	"s0", "s1", "s2", "s3", "s4", "s5", "s6", "s7",
+	"t8", "t9", "k0", "k1", "gp", "sp", "fp", "ra",
+}
+
+// Field extractors, same layout as the CPU's Instruction
+func op(w uint32) uint32    { return w >> 26 }
+func rs(w uint32) uint32    { return (w >> 21) & 0x1f }
+func rt(w uint32) uint32    { return (w >> 16) & 0x1f }
+func rd(w uint32) uint32    { return (w >> 11) & 0x1f }
+func shamt(w uint32) uint32 { return (w >> 6) & 0x1f }
+func funct(w uint32) uint32 { return w & 0x3f }
+func imm(w uint32) uint32   { return w & 0xffff }
+func simm(w uint32) int32   { return int32(int16(w)) }
+
+func reg(r uint32) string {
+	return "$" + REG_NAMES[r]
+}
+
+func hex(v int32) string { //Signed hex, -0x10 rather than 0xfffffff0
+	if v < 0 {
+		return fmt.Sprintf("-0x%x", -int64(v))
+	}
+	return fmt.Sprintf("0x%x", v)
+}
+
+var specialNames = map[uint32]string{
+	0x00: "sll", 0x02: "srl", 0x03: "sra",
+	0x04: "sllv", 0x06: "srlv", 0x07: "srav",
+	0x08: "jr", 0x09: "jalr", 0x0c: "syscall", 0x0d: "break",
+	0x10: "mfhi", 0x11: "mthi", 0x12: "mflo", 0x13: "mtlo",
+	0x18: "mult", 0x19: "multu", 0x1a: "div", 0x1b: "divu",
+	0x20: "add", 0x21: "addu", 0x22: "sub", 0x23: "subu",
+	0x24: "and", 0x25: "or", 0x26: "xor", 0x27: "nor",
+	0x2a: "slt", 0x2b: "sltu",
+}
+
+var primaryNames = map[uint32]string{
+	0x02: "j", 0x03: "jal", 0x04: "beq", 0x05: "bne", 0x06: "blez", 0x07: "bgtz",
+	0x08: "addi", 0x09: "addiu", 0x0a: "slti", 0x0b: "sltiu",
+	0x0c: "andi", 0x0d: "ori", 0x0e: "xori", 0x0f: "lui",
+	0x20: "lb", 0x21: "lh", 0x22: "lwl", 0x23: "lw", 0x24: "lbu", 0x25: "lhu", 0x26: "lwr",
+	0x28: "sb", 0x29: "sh", 0x2a: "swl", 0x2b: "sw", 0x2e: "swr",
+	0x30: "lwc0", 0x31: "lwc1", 0x32: "lwc2", 0x33: "lwc3",
+	0x38: "swc0", 0x39: "swc1", 0x3a: "swc2", 0x3b: "swc3",
+}
+
+// Canonical MIPS I assembly for word, pc resolves branch and jump targets
+func Disassemble(pc uint32, word uint32) string {
+	if pseudo := Pseudo(pc, word); pseudo != "" {
+		return pseudo
+	}
+
+	switch op(word) {
+	case 0x00:
+		return special(word)
+	case 0x01:
+		return bcondz(pc, word)
+	case 0x10, 0x11, 0x12, 0x13:
+		return cop(word)
+	}
+
+	name, ok := primaryNames[op(word)]
+	if !ok {
+		return illegal(word)
+	}
+
+	switch op(word) {
+	case 0x02, 0x03:
+		target, _ := BranchTarget(pc, word)
+		return fmt.Sprintf("%s 0x%08x", name, target)
+	case 0x04, 0x05:
+		target, _ := BranchTarget(pc, word)
+		return fmt.Sprintf("%s %s, %s, 0x%08x", name, reg(rs(word)), reg(rt(word)), target)
+	case 0x06, 0x07:
+		target, _ := BranchTarget(pc, word)
+		return fmt.Sprintf("%s %s, 0x%08x", name, reg(rs(word)), target)
+	case 0x08, 0x09, 0x0a, 0x0b:
+		return fmt.Sprintf("%s %s, %s, %s", name, reg(rt(word)), reg(rs(word)), hex(simm(word)))
+	case 0x0c, 0x0d, 0x0e:
+		return fmt.Sprintf("%s %s, %s, 0x%x", name, reg(rt(word)), reg(rs(word)), imm(word))
+	case 0x0f:
+		return fmt.Sprintf("%s %s, 0x%x", name, reg(rt(word)), imm(word))
+	case 0x30, 0x31, 0x32, 0x33, 0x38, 0x39, 0x3a, 0x3b: //Coprocessor register, not a GPR
+		return fmt.Sprintf("%s $%d, %s(%s)", name, rt(word), hex(simm(word)), reg(rs(word)))
+	default: //Loads and stores
+		return fmt.Sprintf("%s %s, %s(%s)", name, reg(rt(word)), hex(simm(word)), reg(rs(word)))
+	}
+}
+
+// The pseudo-op an assembler would print instead, or "" if there is none
+func Pseudo(pc uint32, word uint32) string {
+	if word == 0 {
+		return "nop"
+	}
+
+	switch op(word) {
+	case 0x00:
+		switch {
+		case (funct(word) == 0x21 || funct(word) == 0x25) && rt(word) == 0: //ADDU, OR
+			return fmt.Sprintf("move %s, %s", reg(rd(word)), reg(rs(word)))
+		case funct(word) == 0x23 && rs(word) == 0: //SUBU
+			return fmt.Sprintf("negu %s, %s", reg(rd(word)), reg(rt(word)))
+		case funct(word) == 0x27 && rt(word) == 0: //NOR
+			return fmt.Sprintf("not %s, %s", reg(rd(word)), reg(rs(word)))
+		}
+	case 0x01:
+		target, _ := BranchTarget(pc, word)
+		if rs(word) == 0 && rt(word) == 0x01 { //BGEZ $zero
+			return fmt.Sprintf("b 0x%08x", target)
+		}
+		if rs(word) == 0 && rt(word) == 0x11 { //BGEZAL $zero
+			return fmt.Sprintf("bal 0x%08x", target)
+		}
+	case 0x04, 0x05:
+		target, _ := BranchTarget(pc, word)
+		switch {
+		case op(word) == 0x04 && rs(word) == 0 && rt(word) == 0:
+			return fmt.Sprintf("b 0x%08x", target)
+		case rt(word) == 0 && op(word) == 0x04:
+			return fmt.Sprintf("beqz %s, 0x%08x", reg(rs(word)), target)
+		case rt(word) == 0:
+			return fmt.Sprintf("bnez %s, 0x%08x", reg(rs(word)), target)
+		}
+	case 0x09: //ADDIU
+		if rs(word) == 0 {
+			return fmt.Sprintf("li %s, %s", reg(rt(word)), hex(simm(word)))
+		}
+	case 0x0d: //ORI
+		if rs(word) == 0 {
+			return fmt.Sprintf("li %s, 0x%x", reg(rt(word)), imm(word))
+		}
+	}
+	return ""
+}
+
+func special(word uint32) string {
+	name, ok := specialNames[funct(word)]
+	if !ok {
+		return illegal(word)
+	}
+
+	switch funct(word) {
+	case 0x00, 0x02, 0x03:
+		return fmt.Sprintf("%s %s, %s, %d", name, reg(rd(word)), reg(rt(word)), shamt(word))
+	case 0x04, 0x06, 0x07:
+		return fmt.Sprintf("%s %s, %s, %s", name, reg(rd(word)), reg(rt(word)), reg(rs(word)))
+	case 0x08:
+		return fmt.Sprintf("%s %s", name, reg(rs(word)))
+	case 0x09:
+		if rd(word) == 31 {
+			return fmt.Sprintf("%s %s", name, reg(rs(word)))
+		}
+		return fmt.Sprintf("%s %s, %s", name, reg(rd(word)), reg(rs(word)))
+	case 0x0c, 0x0d:
+		if code := (word >> 6) & 0xfffff; code != 0 {
+			return fmt.Sprintf("%s 0x%x", name, code)
+		}
+		return name
+	case 0x10, 0x12:
+		return fmt.Sprintf("%s %s", name, reg(rd(word)))
+	case 0x11, 0x13:
+		return fmt.Sprintf("%s %s", name, reg(rs(word)))
+	case 0x18, 0x19, 0x1a, 0x1b:
+		return fmt.Sprintf("%s %s, %s", name, reg(rs(word)), reg(rt(word)))
+	default:
+		return fmt.Sprintf("%s %s, %s, %s", name, reg(rd(word)), reg(rs(word)), reg(rt(word)))
+	}
+}
+
+func bcondz(pc uint32, word uint32) string { //Decoded the way the R3000A does, from bits 16 and 20
+	name := "bltz"
+	if rt(word)&1 != 0 {
+		name = "bgez"
+	}
+	if rt(word)&0x1e == 0x10 {
+		name += "al"
+	}
+
+	target, _ := BranchTarget(pc, word)
+	return fmt.Sprintf("%s %s, 0x%08x", name, reg(rs(word)), target)
+}
+
+func illegal(word uint32) string {
+	return fmt.Sprintf(".word 0x%08x", word)
+}
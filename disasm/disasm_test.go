@@ -0,0 +1,119 @@
+package disasm
+
+import (
+	"testing"
+)
+
+const TEST_PC uint32 = 0x80010000
+
+// Words from the assembler and the text it was given
+var ASSEMBLED = []struct {
+	word uint32
+	text string
+}{
+	// ALU
+	{0x00851021, "addu $v0, $a0, $a1"},
+	{0x012a4022, "sub $t0, $t1, $t2"},
+	{0x0085102a, "slt $v0, $a0, $a1"},
+	{0x00094100, "sll $t0, $t1, 4"},
+	{0x00831007, "srav $v0, $v1, $a0"},
+	{0x00850018, "mult $a0, $a1"},
+	{0x00001010, "mfhi $v0"},
+	{0x27bdffe0, "addiu $sp, $sp, -0x20"},
+	{0x304200ff, "andi $v0, $v0, 0xff"},
+	{0x3c018001, "lui $at, 0x8001"},
+	{0x0000000c, "syscall"},
+	{0x0001000d, "break 0x400"},
+
+	// Pseudo-ops
+	{0x00000000, "nop"},
+	{0x00801021, "move $v0, $a0"},
+	{0x00041023, "negu $v0, $a0"},
+	{0x00801027, "not $v0, $a0"},
+	{0x24020010, "li $v0, 0x10"},
+	{0x3404beef, "li $a0, 0xbeef"},
+
+	// Loads and stores
+	{0x8fa20010, "lw $v0, 0x10($sp)"},
+	{0xa044ffff, "sb $a0, -0x1($v0)"},
+	{0x88880003, "lwl $t0, 0x3($a0)"},
+	{0x94820000, "lhu $v0, 0x0($a0)"},
+	{0xc8800000, "lwc2 $0, 0x0($a0)"},
+	{0xe8580004, "swc2 $24, 0x4($v0)"},
+
+	// Branches and jumps
+	{0x10850004, "beq $a0, $a1, 0x80010014"},
+	{0x10000003, "b 0x80010010"},
+	{0x10800001, "beqz $a0, 0x80010008"},
+	{0x1440fffe, "bnez $v0, 0x8000fffc"},
+	{0x18800002, "blez $a0, 0x8001000c"},
+	{0x1c800002, "bgtz $a0, 0x8001000c"},
+	{0x04800001, "bltz $a0, 0x80010008"},
+	{0x04910001, "bgezal $a0, 0x80010008"},
+	{0x04110001, "bal 0x80010008"},
+	{0x08008000, "j 0x80020000"},
+	{0x0c00448d, "jal 0x80011234"},
+	{0x03e00008, "jr $ra"},
+	{0x0100f809, "jalr $t0"},
+	{0x01001009, "jalr $v0, $t0"},
+
+	// COP0
+	{0x401a6800, "mfc0 $k0, $cause"},
+	{0x40886000, "mtc0 $t0, $sr"},
+	{0x42000010, "rfe"},
+
+	// COP2 transfers
+	{0x48023800, "mfc2 $v0, $7"},
+	{0x4842f800, "cfc2 $v0, $31"},
+	{0x48c8f800, "ctc2 $t0, $31"},
+
+	// GTE commands
+	{0x4a180001, "rtps sf"},
+	{0x4a280030, "rtpt sf"},
+	{0x4b400006, "nclip"},
+	{0x4ae80413, "ncds sf, lm"},
+	{0x4a486012, "mvmva rt, v0, none, sf"},
+	{0x4a43a412, "mvmva llm, ir, bk, lm"},
+	{0x4b58002d, "avsz3 sf"},
+	{0x4a000000, "cop2 0x0000000"},
+
+	// Illegal
+	{0xfc000000, ".word 0xfc000000"},
+}
+
+func TestDisassemble(t *testing.T) {
+	for _, a := range ASSEMBLED {
+		if got := Disassemble(TEST_PC, a.word); got != a.text {
+			t.Errorf("%08x: got %q, want %q", a.word, got, a.text)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	for _, c := range []struct {
+		word             uint32
+		delay, call, ret bool
+		target           uint32
+		hasTarget        bool
+	}{
+		{0x00851021, false, false, false, 0, false},        //addu
+		{0x10850004, true, false, false, 0x80010014, true}, //beq
+		{0x04910001, true, true, false, 0x80010008, true},  //bgezal
+		{0x0c00448d, true, true, false, 0x80011234, true},  //jal
+		{0x03e00008, true, false, true, 0, false},          //jr $ra
+		{0x0100f809, true, true, false, 0, false},          //jalr
+	} {
+		if got := HasDelaySlot(c.word); got != c.delay {
+			t.Errorf("%08x: HasDelaySlot = %v", c.word, got)
+		}
+		if got := IsCall(c.word); got != c.call {
+			t.Errorf("%08x: IsCall = %v", c.word, got)
+		}
+		if got := IsReturn(c.word); got != c.ret {
+			t.Errorf("%08x: IsReturn = %v", c.word, got)
+		}
+		if target, ok := BranchTarget(TEST_PC, c.word); target != c.target || ok != c.hasTarget {
+			t.Errorf("%08x: BranchTarget = 0x%08x, %v", c.word, target, ok)
+		}
+	}
+}
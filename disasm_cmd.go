@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Koops0/GPSXE/disasm"
+	"github.com/Koops0/GPSXE/exe"
+)
+
+// gpsxe disasm [-start addr] [-count n] [-base addr] <bios.bin|game.exe>
+func Disasm_command(args []string) int {
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	start := fs.String("start", "", "First address to disassemble, hex (default: start of the image)")
+	count := fs.Uint("count", 0, "Number of instructions, 0 for the rest of the image")
+	base := fs.String("base", "bfc00000", "Load address for raw images, PS-X EXEs use their header")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gpsxe disasm [flags] <bios.bin|game.exe>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading image:", err)
+		return 1
+	}
+
+	origin, err := parseHex(*base)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Bad -base:", err)
+		return 2
+	}
+
+	if e, err := exe.Parse(data); err == nil {
+		data = e.Payload
+		origin = e.Text
+		fmt.Printf("# PS-X EXE, entry 0x%08x, gp 0x%08x\n", e.PC, e.GP)
+	} else if !errors.Is(err, exe.ErrNotExe) {
+		fmt.Fprintln(os.Stderr, "Error parsing EXE:", err)
+		return 1
+	}
+
+	addr := origin
+	if *start != "" {
+		if addr, err = parseHex(*start); err != nil {
+			fmt.Fprintln(os.Stderr, "Bad -start:", err)
+			return 2
+		}
+	}
+	addr &^= 3
+
+	end := origin + uint32(len(data))&^3
+	if addr < origin || addr >= end {
+		fmt.Fprintf(os.Stderr, "0x%08x is outside the image (0x%08x-0x%08x)\n", addr, origin, end)
+		return 1
+	}
+	if *count != 0 && uint64(addr)+uint64(*count)*4 < uint64(end) {
+		end = addr + uint32(*count)*4
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for ; addr < end; addr += 4 {
+		word := binary.LittleEndian.Uint32(data[addr-origin:])
+		fmt.Fprintf(out, "%08x  %08x  %s\n", addr, word, disasm.Disassemble(addr, word))
+	}
+	return 0
+}
+
+func parseHex(s string) (uint32, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 32)
+	return uint32(v), err
+}
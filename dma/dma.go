@@ -28,7 +28,7 @@ func (d *DMA) New() DMA{
 
 func (d *DMA) Irq() bool { //Return interrupt
 	channel := d.chan_flags & d.chan_irq_en
-	return d.force_irq || (d.chan_irq_en != 0 && channel != 0)
+	return d.force_irq || (d.irq_en && channel != 0)
 }
 
 func (d *DMA) Interrupt() uint32 { //Get interrupt val
@@ -79,6 +79,18 @@ func (d *DMA) Set_interrupt(val uint32) { //Set interrupt
 	d.chan_flags &= ^ack
 }
 
+func (d *DMA) Done(port Port) bool { //Channel completed, true if the IRQ went high
+	before := d.Irq()
+
+	d.Channels[port].Done()
+
+	if d.chan_irq_en&(1<<port) != 0 {
+		d.chan_flags |= 1 << port
+	}
+
+	return !before && d.Irq()
+}
+
 func (d *DMA) Control() uint32 {
 	return d.control
 }
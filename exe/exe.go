@@ -0,0 +1,111 @@
+package exe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	HEADER_SIZE uint32 = 0x800
+	RAM_SIZE    uint32 = 2 * 1024 * 1024
+	MAGIC              = "PS-X EXE"
+)
+
+// PS-X EXE header, the rest of the 2KB header is padding and the region string
+type Header struct {
+	PC          uint32 //Initial program counter
+	GP          uint32 //Initial $gp
+	Text        uint32 //Where the payload goes in RAM
+	TextSize    uint32
+	Data        uint32 //Unused by the BIOS
+	DataSize    uint32
+	Bss         uint32 //Zero filled
+	BssSize     uint32
+	StackBase   uint32 //Initial $sp and $fp if not 0
+	StackOffset uint32
+}
+
+type Exe struct {
+	Header
+	Payload []uint8
+}
+
+var ErrNotExe = errors.New("not a PS-X EXE")
+
+func Load(path string) (*Exe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return e, nil
+}
+
+func Parse(data []uint8) (*Exe, error) {
+	if uint32(len(data)) < HEADER_SIZE {
+		return nil, fmt.Errorf("%w: file is %d bytes, the header alone is %d", ErrNotExe, len(data), HEADER_SIZE)
+	}
+	if string(data[:len(MAGIC)]) != MAGIC {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrNotExe, data[:len(MAGIC)])
+	}
+
+	word := func(offset int) uint32 {
+		return binary.LittleEndian.Uint32(data[offset:])
+	}
+
+	h := Header{
+		PC:          word(0x10),
+		GP:          word(0x14),
+		Text:        word(0x18),
+		TextSize:    word(0x1c),
+		Data:        word(0x20),
+		DataSize:    word(0x24),
+		Bss:         word(0x28),
+		BssSize:     word(0x2c),
+		StackBase:   word(0x30),
+		StackOffset: word(0x34),
+	}
+
+	payload := uint32(len(data)) - HEADER_SIZE
+	if h.TextSize > payload {
+		return nil, fmt.Errorf("header says 0x%x bytes of text but the file only has 0x%x", h.TextSize, payload)
+	}
+	if err := checkRange("text", h.Text, h.TextSize); err != nil {
+		return nil, err
+	}
+	if err := checkRange("bss", h.Bss, h.BssSize); err != nil {
+		return nil, err
+	}
+	if h.PC%4 != 0 {
+		return nil, fmt.Errorf("unaligned initial PC 0x%08x", h.PC)
+	}
+
+	return &Exe{
+		Header:  h,
+		Payload: data[HEADER_SIZE : HEADER_SIZE+h.TextSize],
+	}, nil
+}
+
+func (h *Header) StackPointer() (uint32, bool) { //Only set when the base is not 0
+	if h.StackBase == 0 {
+		return 0, false
+	}
+	return h.StackBase + h.StackOffset, true
+}
+
+func checkRange(name string, addr uint32, size uint32) error { //Must fit in main RAM
+	if size == 0 {
+		return nil
+	}
+	offset := addr & 0x1fffffff
+	if offset >= RAM_SIZE || size > RAM_SIZE-offset {
+		return fmt.Errorf("%s 0x%08x+0x%x does not fit in RAM", name, addr, size)
+	}
+	return nil
+}
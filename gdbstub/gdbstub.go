@@ -0,0 +1,241 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/Koops0/GPSXE/disasm"
+)
+
+// What the stub needs to see of the CPU
+type Target interface {
+	PC() uint32
+	SetPC(val uint32)
+	Reg(index uint32) uint32
+	SetRegister(index uint32, val uint32)
+	Hi() uint32
+	SetHi(val uint32)
+	Lo() uint32
+	SetLo(val uint32)
+	Cop0(index uint32) uint32
+	Peek8(addr uint32) (uint8, bool) //Memory access without side effects or watchers
+	Peek32(addr uint32) (uint32, bool)
+	Poke8(addr uint32, val uint8) bool
+}
+
+const (
+	SIGINT  = 2
+	SIGTRAP = 5
+
+	POLL_INTERVAL = 4096 //Instructions between checks for a ^C from gdb
+)
+
+// GDB remote serial protocol server, one client at a time
+type Stub struct {
+	conn    net.Conn
+	wlock   sync.Mutex
+	packets chan string //Payloads from the client, "\x03" for a break request
+	pending []string    //Packets that arrived while running, answered at the next stop
+
+	breakpoints map[uint32]bool
+	stepping    bool
+	delay       bool //The next instruction sits in a branch delay slot
+	resumed     bool //Client is waiting for a stop reply
+	signal      int
+	poll        int
+	detached    bool
+	quit        bool
+}
+
+// Waits for gdb to connect on addr, the CPU stays stopped until it says otherwise
+func Listen(addr string) (*Stub, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	log.Printf("Waiting for gdb on %s", ln.Addr())
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("gdb connected from %s", conn.RemoteAddr())
+	return newStub(conn), nil
+}
+
+func newStub(conn net.Conn) *Stub {
+	s := &Stub{
+		conn:        conn,
+		packets:     make(chan string, 16),
+		breakpoints: make(map[uint32]bool),
+		stepping:    true, //Stop on the first instruction
+		signal:      SIGTRAP,
+	}
+	go s.read()
+	return s
+}
+
+func (s *Stub) Quitting() bool {
+	return s.quit
+}
+
+// Called before every instruction
+func (s *Stub) Before(t Target) {
+	if s.detached {
+		return
+	}
+
+	pc := t.PC()
+	signal := 0
+
+	if s.breakpoints[pc] || (s.stepping && !s.delay) { //A branch and its delay slot are one step
+		signal = SIGTRAP
+	} else if s.poll++; s.poll >= POLL_INTERVAL {
+		s.poll = 0
+		select {
+		case p, ok := <-s.packets:
+			if !ok {
+				s.detach()
+				return
+			}
+			if p == "\x03" {
+				signal = SIGINT
+			} else {
+				s.pending = append(s.pending, p)
+			}
+		default:
+		}
+	}
+
+	if signal != 0 {
+		s.stepping = false
+		s.signal = signal
+		if s.resumed {
+			s.send(fmt.Sprintf("S%02x", signal))
+			s.resumed = false
+		}
+		s.serve(t)
+		if s.detached {
+			return
+		}
+	}
+
+	word, _ := t.Peek32(pc &^ 3)
+	s.delay = disasm.HasDelaySlot(word)
+}
+
+func (s *Stub) serve(t Target) { //Answer packets until the client resumes
+	for {
+		p, ok := s.next()
+		if !ok {
+			break
+		}
+		if p == "\x03" {
+			continue
+		}
+
+		reply, resume := s.handle(t, p)
+		if s.detached {
+			return
+		}
+		if resume {
+			s.resumed = true
+			return
+		}
+		s.send(reply)
+	}
+	s.detach() //Connection gone
+}
+
+func (s *Stub) next() (string, bool) { //Buffered packets first, false once the connection is gone
+	if len(s.pending) > 0 {
+		p := s.pending[0]
+		s.pending = s.pending[1:]
+		return p, true
+	}
+	p, ok := <-s.packets
+	return p, ok
+}
+
+func (s *Stub) detach() {
+	if !s.detached {
+		log.Printf("gdb detached, resuming")
+	}
+	s.detached = true
+	s.stepping = false
+	s.conn.Close()
+}
+
+func (s *Stub) read() { //Split the byte stream into packets, ack each one
+	defer close(s.packets)
+	r := bufio.NewReader(s.conn)
+
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		switch c {
+		case 0x03:
+			s.packets <- "\x03"
+		case '$':
+			payload, err := r.ReadString('#')
+			if err != nil {
+				return
+			}
+			payload = payload[:len(payload)-1]
+
+			sum := make([]byte, 2)
+			if _, err := io.ReadFull(r, sum); err != nil {
+				return
+			}
+
+			if want, err := strconv.ParseUint(string(sum), 16, 8); err != nil || uint8(want) != checksum(payload) {
+				s.write("-")
+				continue
+			}
+			s.write("+")
+			s.packets <- unescape(payload)
+		default:
+			//Acks and line noise
+		}
+	}
+}
+
+func (s *Stub) send(payload string) {
+	s.write(fmt.Sprintf("$%s#%02x", payload, checksum(payload)))
+}
+
+func (s *Stub) write(data string) {
+	s.wlock.Lock()
+	defer s.wlock.Unlock()
+	s.conn.Write([]byte(data))
+}
+
+func checksum(payload string) uint8 {
+	sum := uint8(0)
+	for i := 0; i < len(payload); i++ {
+		sum += payload[i]
+	}
+	return sum
+}
+
+func unescape(payload string) string { //'}' escapes the next byte, XORed with 0x20
+	out := make([]byte, 0, len(payload))
+	for i := 0; i < len(payload); i++ {
+		if payload[i] == '}' && i+1 < len(payload) {
+			i++
+			out = append(out, payload[i]^0x20)
+		} else {
+			out = append(out, payload[i])
+		}
+	}
+	return string(out)
+}
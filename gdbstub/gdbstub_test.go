@@ -0,0 +1,182 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	TEST_ENTRY    uint32 = 0x100
+	TEST_RAM_SIZE        = 0x1000
+)
+
+// Runs NOPs out of a small RAM, enough for the stub to stop, step and poke at
+type fakeCPU struct {
+	pc     uint32
+	regs   [32]uint32
+	hi, lo uint32
+	ram    [TEST_RAM_SIZE]uint8
+}
+
+func (c *fakeCPU) PC() uint32                           { return c.pc }
+func (c *fakeCPU) SetPC(val uint32)                     { c.pc = val }
+func (c *fakeCPU) Reg(index uint32) uint32              { return c.regs[index] }
+func (c *fakeCPU) SetRegister(index uint32, val uint32) { c.regs[index] = val }
+func (c *fakeCPU) Hi() uint32                           { return c.hi }
+func (c *fakeCPU) SetHi(val uint32)                     { c.hi = val }
+func (c *fakeCPU) Lo() uint32                           { return c.lo }
+func (c *fakeCPU) SetLo(val uint32)                     { c.lo = val }
+func (c *fakeCPU) Cop0(index uint32) uint32             { return 0 }
+
+func (c *fakeCPU) Peek8(addr uint32) (uint8, bool) {
+	if addr >= TEST_RAM_SIZE {
+		return 0, false
+	}
+	return c.ram[addr], true
+}
+
+func (c *fakeCPU) Peek32(addr uint32) (uint32, bool) {
+	v := uint32(0)
+	for n := uint32(0); n < 4; n++ {
+		b, ok := c.Peek8(addr + n)
+		if !ok {
+			return 0, false
+		}
+		v |= uint32(b) << (8 * n)
+	}
+	return v, true
+}
+
+func (c *fakeCPU) Poke8(addr uint32, val uint8) bool {
+	if addr >= TEST_RAM_SIZE {
+		return false
+	}
+	c.ram[addr] = val
+	return true
+}
+
+// The gdb side of the pipe
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) send(payload string) {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "$%s#%02x", payload, checksum(payload))
+	if ack, err := c.r.ReadByte(); err != nil || ack != '+' {
+		c.t.Fatalf("%q: no ack, got %q %v", payload, ack, err)
+	}
+}
+
+func (c *client) reply() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.r.ReadString('$'); err != nil {
+		c.t.Fatalf("no reply: %v", err)
+	}
+	payload, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatalf("truncated reply: %v", err)
+	}
+	sum := make([]byte, 2)
+	if _, err := io.ReadFull(c.r, sum); err != nil {
+		c.t.Fatalf("truncated reply: %v", err)
+	}
+	return strings.TrimSuffix(payload, "#")
+}
+
+func (c *client) expect(payload string, want string) {
+	c.t.Helper()
+	c.send(payload)
+	if got := c.reply(); got != want {
+		c.t.Fatalf("%q: got %q, want %q", payload, got, want)
+	}
+}
+
+func (c *client) request(payload string) string {
+	c.t.Helper()
+	c.send(payload)
+	return c.reply()
+}
+
+func (c *client) expectStop(want string) {
+	c.t.Helper()
+	if got := c.reply(); got != want {
+		c.t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestScriptedSession(t *testing.T) {
+	near, far := net.Pipe()
+	defer near.Close()
+
+	cpu := &fakeCPU{pc: TEST_ENTRY}
+	stub := newStub(far)
+	done := make(chan struct{})
+	go func() { //The emulator loop
+		defer close(done)
+		for !stub.Quitting() {
+			stub.Before(cpu)
+			cpu.pc += 4
+		}
+	}()
+
+	c := &client{t: t, conn: near, r: bufio.NewReader(near)}
+
+	c.expect("?", "S05") //Stopped on the first instruction
+	c.expect("", "")
+
+	// Registers
+	regs := c.request("g")
+	if len(regs) != NUM_REGS*8 {
+		t.Fatalf("g: %d hex digits, want %d", len(regs), NUM_REGS*8)
+	}
+	if pc := regs[REG_PC*8 : (REG_PC+1)*8]; pc != encodeWord(TEST_ENTRY) {
+		t.Fatalf("g: pc %s", pc)
+	}
+	written := regs[:8] + encodeWord(0x12345678) + regs[16:CPU_REGS*8]
+	c.expect("G"+written, "OK")
+	c.expect("p1", encodeWord(0x12345678))
+	if got := c.request("g"); got[:CPU_REGS*8] != written {
+		t.Fatalf("g after G: %s", got)
+	}
+
+	// Memory
+	c.expect("M200,4:deadbeef", "OK")
+	c.expect("m200,4", "deadbeef")
+	c.expect("m201,2", "adbe")
+	c.expect("mffe,4", "0000") //Short read at the end of RAM
+	c.expect("m2000,4", "E01")
+	c.expect("M2000,1:00", "E01")
+
+	// Breakpoints, continue and step
+	c.expect("Z0,120,4", "OK")
+	c.send("c")
+	c.expectStop("S05")
+	c.expect("p25", encodeWord(0x120))
+	c.expect("z0,120,4", "OK")
+	c.send("s")
+	c.expectStop("S05")
+	c.expect("p25", encodeWord(0x124))
+
+	// Interrupt while running, a packet sent before it is answered once stopped
+	c.send("c")
+	c.send("?")
+	near.Write([]byte{0x03})
+	c.expectStop("S02")
+	c.expectStop("S02")
+
+	c.send("k")
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("k didn't stop the emulator loop")
+	}
+}
@@ -0,0 +1,213 @@
+package gdbstub
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MIPS register numbering as gdb expects it without a target description
+const (
+	REG_SR       = 32
+	REG_LO       = 33
+	REG_HI       = 34
+	REG_BADVADDR = 35
+	REG_CAUSE    = 36
+	REG_PC       = 37
+
+	CPU_REGS = 38
+	NUM_REGS = 72 //Plus 32 FPRs, FCSR and FIR, always zero on the PSX
+
+	MAX_MEM_READ = 0x1000
+)
+
+func (s *Stub) handle(t Target, p string) (string, bool) { //Reply, or true to resume execution
+	if len(p) == 0 { //"$#00"
+		return "", false
+	}
+
+	switch p[0] {
+	case '?':
+		return fmt.Sprintf("S%02x", s.signal), false
+	case 'g':
+		var b strings.Builder
+		for n := uint32(0); n < NUM_REGS; n++ {
+			b.WriteString(encodeWord(readReg(t, n)))
+		}
+		return b.String(), false
+	case 'G':
+		for n := uint32(0); n < CPU_REGS && len(p) >= 1+int(n+1)*8; n++ {
+			v, err := decodeWord(p[1+n*8 : 1+(n+1)*8])
+			if err != nil {
+				return "E01", false
+			}
+			writeReg(t, n, v)
+		}
+		return "OK", false
+	case 'p':
+		n, err := strconv.ParseUint(p[1:], 16, 32)
+		if err != nil {
+			return "E01", false
+		}
+		return encodeWord(readReg(t, uint32(n))), false
+	case 'P':
+		reg, val, ok := strings.Cut(p[1:], "=")
+		n, err := strconv.ParseUint(reg, 16, 32)
+		if !ok || err != nil {
+			return "E01", false
+		}
+		v, err := decodeWord(val)
+		if err != nil {
+			return "E01", false
+		}
+		writeReg(t, uint32(n), v)
+		return "OK", false
+	case 'm':
+		addr, size, err := parseRange(p[1:])
+		if err != nil || size > MAX_MEM_READ {
+			return "E01", false
+		}
+		data := make([]byte, 0, size)
+		for i := uint32(0); i < size; i++ {
+			b, ok := t.Peek8(addr + i)
+			if !ok { //Stop at the first unreadable byte, gdb takes a short read
+				break
+			}
+			data = append(data, b)
+		}
+		if len(data) == 0 && size > 0 {
+			return "E01", false
+		}
+		return hex.EncodeToString(data), false
+	case 'M':
+		header, payload, ok := strings.Cut(p[1:], ":")
+		addr, size, err := parseRange(header)
+		if !ok || err != nil {
+			return "E01", false
+		}
+		data, err := hex.DecodeString(payload)
+		if err != nil || uint32(len(data)) != size {
+			return "E01", false
+		}
+		for i, b := range data {
+			if !t.Poke8(addr+uint32(i), b) {
+				return "E01", false
+			}
+		}
+		return "OK", false
+	case 'c', 's':
+		if len(p) > 1 {
+			addr, err := strconv.ParseUint(p[1:], 16, 32)
+			if err != nil {
+				return "E01", false
+			}
+			t.SetPC(uint32(addr))
+		}
+		s.stepping = p[0] == 's'
+		return "", true
+	case 'Z', 'z':
+		if len(p) < 3 || (p[1] != '0' && p[1] != '1') { //Software and hardware breakpoints, no watchpoints
+			return "", false
+		}
+		fields := strings.Split(p[3:], ",")
+		addr, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return "E01", false
+		}
+		if p[0] == 'Z' {
+			s.breakpoints[uint32(addr)] = true
+		} else {
+			delete(s.breakpoints, uint32(addr))
+		}
+		return "OK", false
+	case 'D':
+		s.send("OK")
+		s.detach()
+		return "", false
+	case 'k':
+		s.quit = true
+		s.detach()
+		return "", false
+	case 'H', 'T':
+		return "OK", false
+	case 'q':
+		switch {
+		case strings.HasPrefix(p, "qSupported"):
+			return fmt.Sprintf("PacketSize=%x", MAX_MEM_READ*2+16), false
+		case p == "qAttached":
+			return "1", false
+		case p == "qC":
+			return "QC1", false
+		case p == "qfThreadInfo":
+			return "m1", false
+		case p == "qsThreadInfo":
+			return "l", false
+		}
+	}
+	return "", false //Unsupported
+}
+
+func readReg(t Target, n uint32) uint32 {
+	switch {
+	case n < 32:
+		return t.Reg(n)
+	case n == REG_SR:
+		return t.Cop0(12)
+	case n == REG_LO:
+		return t.Lo()
+	case n == REG_HI:
+		return t.Hi()
+	case n == REG_BADVADDR:
+		return t.Cop0(8)
+	case n == REG_CAUSE:
+		return t.Cop0(13)
+	case n == REG_PC:
+		return t.PC()
+	default:
+		return 0
+	}
+}
+
+func writeReg(t Target, n uint32, v uint32) { //COP0 and FPU writes are dropped
+	switch {
+	case n > 0 && n < 32:
+		t.SetRegister(n, v)
+	case n == REG_LO:
+		t.SetLo(v)
+	case n == REG_HI:
+		t.SetHi(v)
+	case n == REG_PC:
+		if v != t.PC() { //'G' writes everything back, don't lose a pending branch
+			t.SetPC(v)
+		}
+	}
+}
+
+func encodeWord(v uint32) string { //Target byte order
+	return fmt.Sprintf("%02x%02x%02x%02x", uint8(v), uint8(v>>8), uint8(v>>16), uint8(v>>24))
+}
+
+func decodeWord(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return 0, fmt.Errorf("bad register value %q", s)
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24, nil
+}
+
+func parseRange(s string) (uint32, uint32, error) { //"addr,length"
+	a, l, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("bad range %q", s)
+	}
+	addr, err := strconv.ParseUint(a, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := strconv.ParseUint(l, 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(addr), uint32(size), nil
+}
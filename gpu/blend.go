@@ -0,0 +1,28 @@
+package gpu
+
+// Bit 15 of a VRAM pixel. Set pixels can be protected from drawing, and on texels it
+// marks the ones semi-transparent primitives blend.
+const MASK_BIT uint16 = 0x8000
+
+// Blends the 15 bit pixel being drawn (front) with the one in VRAM (back), per 5 bit channel
+func Blend(back uint16, front uint16, mode BlendMode) uint16 {
+	var out uint16
+	for shift := uint16(0); shift < 15; shift += 5 {
+		b := int32((back >> shift) & 0x1F)
+		f := int32((front >> shift) & 0x1F)
+
+		var c int32
+		switch mode {
+		case BlendAverage:
+			c = (b + f) >> 1
+		case BlendAdd:
+			c = b + f
+		case BlendSubtract:
+			c = b - f
+		case BlendQuarter:
+			c = b + f>>2
+		}
+		out |= uint16(max(0, min(c, 0x1F))) << shift
+	}
+	return out
+}
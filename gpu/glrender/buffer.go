@@ -1,4 +1,4 @@
-package gpu
+package glrender
 
 import (
 	"github.com/go-gl/gl/v4.6-core/gl"
@@ -0,0 +1,81 @@
+#version 460 core
+
+in vec3 color;
+in vec2 uv;
+flat in uvec4 attributes; // page, clut, window, flags as packed by NewGLAttributes
+out vec4 fragColor;
+
+uniform usampler2D vram;
+uniform int pass; // 0 draws everything, 1 only opaque pixels and 2 only semi-transparent ones
+uniform sampler2D frame; // What has been drawn so far, bit 15 is kept in alpha
+uniform int mask; // 1 sets bit 15 on drawn pixels, 2 leaves pixels that have it alone
+
+const uint TEXTURED = 1u;
+const uint RAW_TEXTURE = 2u;
+const uint SEMITRANSPARENT = 4u;
+const int MASK_SET = 1;
+const int MASK_CHECK = 2;
+
+uint vram_get(int x, int y){
+    return texelFetch(vram, ivec2(x & 1023, y & 511), 0).r;
+}
+
+uint texture_window(uint coord, uint mask, uint offset){
+    return (coord & ~(mask * 8u)) | ((offset & mask) * 8u);
+}
+
+// Port of VRAM.Texel in gpu/texture.go, keep the two in step
+uint texel(uvec2 coord){
+    uint page = attributes.x;
+    uint clut = attributes.y;
+    uint window = attributes.z;
+
+    int u = int(texture_window(coord.x, window & 31u, (window >> 10) & 31u));
+    int v = int(texture_window(coord.y, (window >> 5) & 31u, (window >> 15) & 31u));
+    int x = int(page & 15u) * 64;
+    int y = int((page >> 4) & 1u) * 256 + v;
+    int clutX = int(clut & 63u) * 16;
+    int clutY = int((clut >> 6) & 511u);
+
+    switch ((page >> 7) & 3u) {
+    case 0u:
+        return vram_get(clutX + int((vram_get(x + u / 4, y) >> ((u & 3) * 4)) & 15u), clutY);
+    case 1u:
+        return vram_get(clutX + int((vram_get(x + u / 2, y) >> ((u & 1) * 8)) & 255u), clutY);
+    default:
+        return vram_get(x + u, y);
+    }
+}
+
+void keep(bool semi){
+    if ((pass == 1 && semi) || (pass == 2 && !semi)) {
+        discard;
+    }
+}
+
+void main(){
+    bool semi = (attributes.w & SEMITRANSPARENT) != 0u;
+    bool set = (mask & MASK_SET) != 0;
+
+    if ((mask & MASK_CHECK) != 0 && texelFetch(frame, ivec2(gl_FragCoord.xy), 0).a >= 0.5) {
+        discard;
+    }
+
+    if ((attributes.w & TEXTURED) == 0u) {
+        keep(semi);
+        fragColor = vec4(color, set ? 1.0 : 0.0);
+        return;
+    }
+
+    uint t = texel(uvec2(ivec2(floor(uv)) & 255));
+    if (t == 0u) {
+        discard;
+    }
+    keep(semi && (t & 0x8000u) != 0u); // Only texels with bit 15 blend
+
+    vec3 texColor = vec3(float(t & 31u), float((t >> 5) & 31u), float((t >> 10) & 31u)) * 8.0 / 255.0;
+    if ((attributes.w & RAW_TEXTURE) == 0u) {
+        texColor = min(texColor * color * (255.0 / 128.0), 1.0); // 0x80 is 1.0
+    }
+    fragColor = vec4(texColor, set || (t & 0x8000u) != 0u ? 1.0 : 0.0); // Texels bring their own bit 15
+}
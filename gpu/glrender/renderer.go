@@ -0,0 +1,616 @@
+package glrender
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-gl/gl/v4.6-core/gl"
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/Koops0/GPSXE/gpu"
+)
+
+// SDL window drawn with OpenGL 4.6
+type GLRenderer struct {
+	sdlc           	error
+	window         	*sdl.Window
+	context        	sdl.GLContext
+	vertexShader   	uint32
+	fragmentShader 	uint32
+	program        	uint32
+	vao            	uint32
+	positions      	Buffer[gpu.Position]
+	colours        	Buffer[gpu.Colour]
+	uvs            	Buffer[GLTexCoord]
+	attributes     	Buffer[GLAttributes]
+	nVertices      	uint32
+	offset			int32
+	vram			*gpu.VRAM
+	vramTexture		uint32 //Copy of vram for the fragment shader
+	framebuffer		uint32 //Primitives are drawn here at VRAM coordinates
+	frameTexture	uint32
+	textured		bool //Batch samples VRAM
+	semi			bool //Batch is semi-transparent primitives only, all using blend
+	blend			gpu.BlendMode
+	pass			int32 //Uniform picking which pixels a draw keeps
+	mask			int32 //Uniform with the 0xE6 bits
+	checkMask		bool //Primitives are drawn one by one, each reading what the last wrote
+	dirty			bool //Drawn to since vram was last read back
+	pixels			[]uint8 //RGBA staging for framebuffer transfers
+}
+
+// Texture coordinates are interpolated and can run past 255, e.g. to the far edge of a sprite
+type GLTexCoord struct {
+	U int16
+	V int16
+}
+
+// Attributes the fragment shader needs, packed like the GP0 words they come from
+type GLAttributes struct {
+	Page   uint32 //Texpage: X/64, Y/256 in bit 4, depth in bits 7-8
+	Clut   uint32 //X/16, Y in bits 6-14
+	Window uint32 //0xE2: mask X, mask Y, offset X, offset Y in 5 bit fields
+	Flags  uint32
+}
+
+const (
+	GL_TEXTURED        uint32 = 1
+	GL_RAW_TEXTURE     uint32 = 2
+	GL_SEMITRANSPARENT uint32 = 4
+)
+
+// Semi-transparent batches are drawn twice, opaque pixels first and then the blended ones
+const (
+	GL_PASS_ALL    int32 = 0
+	GL_PASS_OPAQUE int32 = 1
+	GL_PASS_BLEND  int32 = 2
+)
+
+// Bit 15 is the framebuffer's alpha, set by the shader and checked by sampling the framebuffer
+const (
+	GL_MASK_SET   int32 = 1
+	GL_MASK_CHECK int32 = 2
+)
+
+func NewGLAttributes(attr gpu.Attributes) GLAttributes {
+	a := GLAttributes{
+		Page:   uint32(attr.PageX/64) | uint32(attr.PageY/256)<<4 | uint32(attr.Depth)<<7,
+		Clut:   uint32(attr.ClutX/16) | uint32(attr.ClutY)<<6,
+		Window: uint32(attr.WindowMaskX) | uint32(attr.WindowMaskY)<<5 | uint32(attr.WindowOffsetX)<<10 | uint32(attr.WindowOffsetY)<<15,
+	}
+	if attr.Textured {
+		a.Flags |= GL_TEXTURED
+	}
+	if attr.RawTexture {
+		a.Flags |= GL_RAW_TEXTURE
+	}
+	if attr.SemiTransparent {
+		a.Flags |= GL_SEMITRANSPARENT
+	}
+	return a
+}
+
+func (r GLRenderer) New() GLRenderer {
+
+	var initFlags uint32 = sdl.INIT_EVERYTHING
+
+	if err := sdl.Init(initFlags); err != nil {
+		// Handle the error, for example, log it, return it, etc.
+		fmt.Printf("SDL could not initialize! SDL_Error: %s\n", sdl.GetError())
+	} else {
+		fmt.Println("SDL initialized")
+	}
+	r.sdlc = sdl.Init(initFlags)
+
+	// Set Attributes
+	sdl.GLSetAttribute(sdl.GLattr(sdl.GL_CONTEXT_MAJOR_VERSION), 4)
+	sdl.GLSetAttribute(sdl.GLattr(sdl.GL_CONTEXT_MINOR_VERSION), 4)
+
+	window, err := sdl.CreateWindow("Go PSX Emulator", int32(sdl.WINDOWPOS_CENTERED),
+		int32(sdl.WINDOWPOS_CENTERED), 1024, 512, uint32(sdl.WINDOW_OPENGL))
+	if err != nil {
+		fmt.Printf("Window could not be created! SDL_Error: %s\n", sdl.GetError())
+	}
+
+	r.window = window
+	r.context, err = window.GLCreateContext()
+	if err != nil {
+		fmt.Printf("OpenGL context could not be created! SDL_Error: %s\n", sdl.GetError())
+	}
+
+	//Load GL
+	if err := gl.InitWithProcAddrFunc(sdl.GLGetProcAddress); err != nil {
+		fmt.Println(err)
+	}
+
+	//fully init
+	gl.Init()
+	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	window.GLSwap()
+
+	//Slurp contents of vs and fs
+	vs, err := os.ReadFile("ps1.vs")
+	if err != nil {
+		fmt.Println("Failed to load vertex shader")
+	}
+	fs, err := os.ReadFile("ps1.fs")
+	if err != nil {
+		fmt.Println("Failed to load fragment shader")
+	}
+
+	// Convert the file contents to a string
+	vsSrc := string(vs)
+	fsSrc := string(fs)
+
+	vertexShader := CompileShader(vsSrc, gl.VERTEX_SHADER)
+	fragmentShader := CompileShader(fsSrc, gl.FRAGMENT_SHADER)
+	program := LinkProgram([]uint32{vertexShader, fragmentShader})
+	gl.UseProgram(program)
+
+	r.vertexShader = vertexShader
+	r.fragmentShader = fragmentShader
+	r.program = program
+
+	//VAO
+	vao := uint32(0)
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+	r.vao = vao
+
+	//Vertex attributes, each from its own buffer
+	var positionsBuffer *Buffer[gpu.Position] = new(Buffer[gpu.Position])
+	positions := positionsBuffer.New()
+	index := FindProgAttrib(program, "vertex_position")
+	gl.EnableVertexAttribArray(index)
+	gl.VertexAttribIPointer(index, 2, gl.SHORT, 0, nil)
+
+	var coloursBuffer *Buffer[gpu.Colour] = new(Buffer[gpu.Colour])
+	colours := coloursBuffer.New()
+	index = FindProgAttrib(program, "vertex_color")
+	gl.EnableVertexAttribArray(index)
+	gl.VertexAttribIPointer(index, 3, gl.UNSIGNED_BYTE, 0, nil)
+
+	var uvsBuffer *Buffer[GLTexCoord] = new(Buffer[GLTexCoord])
+	uvs := uvsBuffer.New()
+	index = FindProgAttrib(program, "vertex_uv")
+	gl.EnableVertexAttribArray(index)
+	gl.VertexAttribPointer(index, 2, gl.SHORT, false, 0, nil)
+
+	var attributesBuffer *Buffer[GLAttributes] = new(Buffer[GLAttributes])
+	attributes := attributesBuffer.New()
+	index = FindProgAttrib(program, "vertex_attributes")
+	gl.EnableVertexAttribArray(index)
+	gl.VertexAttribIPointer(index, 4, gl.UNSIGNED_INT, 0, nil)
+
+	r.positions = positions
+	r.colours = colours
+	r.uvs = uvs
+	r.attributes = attributes
+	r.nVertices = 0
+
+	//offset
+	offset := FindProgUniform(program, "offset")
+	gl.Uniform2i(offset, 0, 0)
+	r.offset = offset
+
+	//VRAM as 16 bit texels, decoded by the fragment shader
+	gl.GenTextures(1, &r.vramTexture)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, r.vramTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R16UI, gpu.VRAM_WIDTH, gpu.VRAM_HEIGHT, 0, gl.RED_INTEGER, gl.UNSIGNED_SHORT, nil)
+	gl.Uniform1i(FindProgUniform(program, "vram"), 0)
+
+	//Offscreen VRAM sized target, the display area is copied to the window from there
+	gl.GenTextures(1, &r.frameTexture)
+	gl.ActiveTexture(gl.TEXTURE1) //Also sampled for the mask check
+	gl.BindTexture(gl.TEXTURE_2D, r.frameTexture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, gpu.VRAM_WIDTH, gpu.VRAM_HEIGHT, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.Uniform1i(FindProgUniform(program, "frame"), 1)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.GenFramebuffers(1, &r.framebuffer)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, r.framebuffer)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, r.frameTexture, 0)
+	gl.ClearColor(0.0, 0.0, 0.0, 0.0) //Mask bits start clear
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	r.pass = FindProgUniform(program, "pass")
+	gl.Uniform1i(r.pass, GL_PASS_ALL)
+	r.mask = FindProgUniform(program, "mask")
+	gl.Uniform1i(r.mask, 0)
+
+	r.vram = gpu.NewVRAM()
+	r.pixels = make([]uint8, gpu.VRAM_WIDTH*gpu.VRAM_HEIGHT*4)
+
+	return r
+}
+
+func CompileShader(source string, shaderType uint32) uint32 {
+	shader := gl.CreateShader(shaderType)
+
+	//compile
+	cStr := gl.Str(source + "\x00")
+	gl.ShaderSource(shader, 1, &cStr, nil)
+	gl.CompileShader(shader)
+
+	//check
+	status := int32(gl.FALSE)
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		panic("Failed to compile shader")
+	}
+	return shader
+}
+
+func LinkProgram(shaders []uint32) uint32 {
+	program := gl.CreateProgram()
+
+	for _, shader := range shaders {
+		gl.AttachShader(program, shader)
+	}
+
+	gl.LinkProgram(program)
+
+	status := int32(gl.FALSE)
+	gl.GetShaderiv(program, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		panic("Linkage Failed")
+	}
+
+	return program
+}
+
+func FindProgUniform(program uint32, name string) int32 {
+	cStr := gl.Str(name + "\x00")
+	index := gl.GetUniformLocation(program, cStr)
+	if index < 0 {
+		panic("Failed to find uniform")
+	}
+	return index
+}
+
+func FindProgAttrib(program uint32, name string) uint32 {
+	cStr := gl.Str(name + "\x00")
+	index := gl.GetAttribLocation(program, cStr)
+	if index < 0 {
+		panic("Failed to find attribute")
+	}
+	return uint32(index)
+}
+
+func (r *GLRenderer) PushTriangle(positions []gpu.Position, colours []gpu.Colour, uvs []gpu.TexCoord, attr gpu.Attributes) {
+	r.pushTriangle(positions, colours, widenUVs(uvs), attr)
+}
+
+func (r *GLRenderer) PushQuad(positions []gpu.Position, colours []gpu.Colour, uvs []gpu.TexCoord, attr gpu.Attributes) {
+	r.pushQuad(positions, colours, widenUVs(uvs), attr)
+}
+
+func widenUVs(uvs []gpu.TexCoord) []GLTexCoord {
+	if uvs == nil {
+		return nil
+	}
+	wide := make([]GLTexCoord, len(uvs))
+	for i, uv := range uvs {
+		wide[i] = GLTexCoord{U: int16(uv.U), V: int16(uv.V)}
+	}
+	return wide
+}
+
+func (r *GLRenderer) pushTriangle(positions []gpu.Position, colours []gpu.Colour, uvs []GLTexCoord, attr gpu.Attributes) {
+	r.useBlend(attr)
+	if r.checkMask && r.nVertices > 0 { //Must see the mask bits the previous primitive set
+		r.Draw()
+	}
+	if r.nVertices+3 > VERTEX_BUFFER_LEN {
+		fmt.Println("Too many vertices, forcing draw")
+		r.Draw()
+	}
+
+	a := NewGLAttributes(attr)
+	for i := 0; i < 3; i++ {
+		r.pushVertex(positions, colours, uvs, a, i)
+	}
+	r.textured = r.textured || attr.Textured
+}
+
+func (r *GLRenderer) pushQuad(positions []gpu.Position, colours []gpu.Colour, uvs []GLTexCoord, attr gpu.Attributes) {
+	r.useBlend(attr)
+	if r.checkMask && r.nVertices > 0 { //Must see the mask bits the previous primitive set
+		r.Draw()
+	}
+	if r.nVertices+6 > VERTEX_BUFFER_LEN {
+		fmt.Println("Too many vertices, forcing draw")
+		r.Draw()
+	}
+
+	a := NewGLAttributes(attr)
+
+	//Tri 1
+	for i := 0; i < 3; i++ {
+		r.pushVertex(positions, colours, uvs, a, i)
+	}
+
+	//Tri 2
+	for i := 1; i < 4; i++ {
+		r.pushVertex(positions, colours, uvs, a, i)
+	}
+	r.textured = r.textured || attr.Textured
+}
+
+// A batch is all opaque or all semi-transparent with one blend mode. The passes of a mixed one
+// would put a later opaque primitive under an earlier semi-transparent one.
+func (r *GLRenderer) useBlend(attr gpu.Attributes) {
+	if r.nVertices > 0 && (attr.SemiTransparent != r.semi || (r.semi && r.blend != attr.Blend)) {
+		r.Draw()
+	}
+	r.semi = attr.SemiTransparent
+	r.blend = attr.Blend
+}
+
+func (r *GLRenderer) pushVertex(positions []gpu.Position, colours []gpu.Colour, uvs []GLTexCoord, a GLAttributes, i int) {
+	r.positions.Set(r.nVertices, positions[i])
+	r.colours.Set(r.nVertices, colours[i])
+	if uvs != nil {
+		r.uvs.Set(r.nVertices, uvs[i])
+	}
+	r.attributes.Set(r.nVertices, a)
+	r.nVertices++
+}
+
+func (r *GLRenderer) PushLine(positions []gpu.Position, colours []gpu.Colour, attr gpu.Attributes) { //As a one pixel wide quad
+	dx := int32(positions[1].X) - int32(positions[0].X)
+	dy := int32(positions[1].Y) - int32(positions[0].Y)
+
+	ox, oy := int16(0), int16(1) //Mostly horizontal lines grow down
+	if dx*dx < dy*dy {
+		ox, oy = 1, 0 //And vertical ones right
+	}
+
+	quad := []gpu.Position{
+		positions[0],
+		positions[1],
+		{X: positions[0].X + ox, Y: positions[0].Y + oy},
+		{X: positions[1].X + ox, Y: positions[1].Y + oy},
+	}
+	r.pushQuad(quad, []gpu.Colour{colours[0], colours[1], colours[0], colours[1]}, nil, attr)
+}
+
+func (r *GLRenderer) PushRectangle(position gpu.Position, width uint16, height uint16, colour gpu.Colour, uv gpu.TexCoord, attr gpu.Attributes) {
+	w, h := int16(width), int16(height)
+	quad := []gpu.Position{
+		position,
+		{X: position.X + w, Y: position.Y},
+		{X: position.X, Y: position.Y + h},
+		{X: position.X + w, Y: position.Y + h},
+	}
+	var uvs []GLTexCoord
+	if attr.Textured { //Texels step one per pixel, backwards from the far side of the first when flipped
+		u0, v0 := int16(uv.U), int16(uv.V)
+		u1, v1 := u0+w, v0+h
+		if attr.FlipX {
+			u0, u1 = u0+1, u0+1-w
+		}
+		if attr.FlipY {
+			v0, v1 = v0+1, v0+1-h
+		}
+		uvs = []GLTexCoord{{U: u0, V: v0}, {U: u1, V: v0}, {U: u0, V: v1}, {U: u1, V: v1}}
+	}
+	r.pushQuad(quad, []gpu.Colour{colour, colour, colour, colour}, uvs, attr)
+}
+
+func (r *GLRenderer) Draw() {
+	if r.textured { //GL only sees VRAM through this copy, which must have what was drawn
+		r.readback()
+		gl.BindTexture(gl.TEXTURE_2D, r.vramTexture)
+		gl.PixelStorei(gl.UNPACK_ALIGNMENT, 2)
+		gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, gpu.VRAM_WIDTH, gpu.VRAM_HEIGHT, gl.RED_INTEGER, gl.UNSIGNED_SHORT, gl.Ptr(&r.vram.Pixels[0]))
+		r.textured = false
+	}
+
+	//flush to buffer
+	gl.MemoryBarrier(gl.CLIENT_MAPPED_BUFFER_BARRIER_BIT)
+	if r.semi {
+		gl.Uniform1i(r.pass, GL_PASS_OPAQUE)
+		r.drawArrays()
+
+		gl.Enable(gl.BLEND)
+		SetBlendEquation(r.blend)
+		gl.Uniform1i(r.pass, GL_PASS_BLEND)
+		r.drawArrays()
+
+		gl.Disable(gl.BLEND)
+		gl.Uniform1i(r.pass, GL_PASS_ALL)
+		r.semi = false
+	} else {
+		r.drawArrays()
+	}
+
+	//wait
+	sync := gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+
+	//during render
+	for{
+		render := gl.ClientWaitSync(sync, gl.SYNC_FLUSH_COMMANDS_BIT, 10000000)
+		if render == gl.ALREADY_SIGNALED || render == gl.CONDITION_SATISFIED {
+			break
+		}
+	}
+
+	if r.nVertices > 0 {
+		r.dirty = true
+	}
+	r.nVertices = 0
+}
+
+func (r *GLRenderer) drawArrays() {
+	if r.checkMask { //Lets the frame sampler see the previous draw
+		gl.TextureBarrier()
+	}
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(r.nVertices))
+}
+
+// Source is the primitive, destination the framebuffer. Alpha is the mask bit and always the source's.
+func SetBlendEquation(mode gpu.BlendMode) {
+	switch mode {
+	case gpu.BlendAverage:
+		gl.BlendEquationSeparate(gl.FUNC_ADD, gl.FUNC_ADD)
+		gl.BlendColor(0, 0, 0, 0.5)
+		gl.BlendFuncSeparate(gl.CONSTANT_ALPHA, gl.CONSTANT_ALPHA, gl.ONE, gl.ZERO)
+	case gpu.BlendAdd:
+		gl.BlendEquationSeparate(gl.FUNC_ADD, gl.FUNC_ADD)
+		gl.BlendFuncSeparate(gl.ONE, gl.ONE, gl.ONE, gl.ZERO)
+	case gpu.BlendSubtract:
+		gl.BlendEquationSeparate(gl.FUNC_REVERSE_SUBTRACT, gl.FUNC_ADD)
+		gl.BlendFuncSeparate(gl.ONE, gl.ONE, gl.ONE, gl.ZERO)
+	case gpu.BlendQuarter:
+		gl.BlendEquationSeparate(gl.FUNC_ADD, gl.FUNC_ADD)
+		gl.BlendColor(0, 0, 0, 0.25)
+		gl.BlendFuncSeparate(gl.CONSTANT_ALPHA, gl.ONE, gl.ONE, gl.ZERO)
+	}
+}
+
+func (r *GLRenderer)DrawOffset(x int16, y int16){
+	r.Draw()
+	gl.Uniform2i(r.offset, int32(x), int32(y))
+}
+
+func (r *GLRenderer) SetDrawingArea(left uint16, top uint16, right uint16, bottom uint16) {
+	r.Draw()
+	gl.Enable(gl.SCISSOR_TEST)
+	if right < left || bottom < top {
+		gl.Scissor(0, 0, 0, 0)
+		return
+	}
+	gl.Scissor(int32(left), int32(511-bottom), int32(right-left)+1, int32(bottom-top)+1) //GL's origin is bottom left
+}
+
+func (r *GLRenderer) SetDithering(enabled bool) { //The window is true colour, nothing to dither
+}
+
+func (r *GLRenderer) SetMaskBit(set bool, check bool) {
+	r.Draw()
+	flags := int32(0)
+	if set {
+		flags |= GL_MASK_SET
+	}
+	if check {
+		flags |= GL_MASK_CHECK
+	}
+	gl.Uniform1i(r.mask, flags)
+	r.checkMask = check
+}
+
+func (r *GLRenderer) VRAM() *gpu.VRAM {
+	return r.vram
+}
+
+func (r *GLRenderer) SyncVRAM() {
+	r.Draw()
+	r.readback()
+}
+
+func (r *GLRenderer) readback() { //The whole framebuffer into vram, only when something was drawn
+	if !r.dirty {
+		return
+	}
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 4)
+	gl.ReadPixels(0, 0, gpu.VRAM_WIDTH, gpu.VRAM_HEIGHT, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&r.pixels[0]))
+	for y := 0; y < gpu.VRAM_HEIGHT; y++ {
+		row := r.pixels[(gpu.VRAM_HEIGHT-1-y)*gpu.VRAM_WIDTH*4:] //GL rows count from the bottom
+		for x := 0; x < gpu.VRAM_WIDTH; x++ {
+			r.vram.Pixels[y*gpu.VRAM_WIDTH+x] = rgba5551(row[x*4:])
+		}
+	}
+	r.dirty = false
+}
+
+func (r *GLRenderer) VRAMWritten(x uint16, y uint16, width uint16, height uint16) {
+	r.Draw() //Batched primitives came first
+	for _, sx := range wrapSpans(int(x), int(width), gpu.VRAM_WIDTH) {
+		for _, sy := range wrapSpans(int(y), int(height), gpu.VRAM_HEIGHT) {
+			r.uploadFrame(sx[0], sy[0], sx[1], sy[1])
+		}
+	}
+}
+
+func (r *GLRenderer) uploadFrame(x int, y int, width int, height int) { //VRAM rectangle into the framebuffer
+	if width == 0 || height == 0 {
+		return
+	}
+	for row := 0; row < height; row++ {
+		vy := y + height - 1 - row //Bottom row first
+		for col := 0; col < width; col++ {
+			setRGBA5551(r.pixels[(row*width+col)*4:], r.vram.Pixels[vy*gpu.VRAM_WIDTH+x+col])
+		}
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, r.frameTexture)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(x), int32(gpu.VRAM_HEIGHT-y-height), int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&r.pixels[0]))
+	gl.BindTexture(gl.TEXTURE_2D, r.vramTexture)
+}
+
+func wrapSpans(start int, length int, size int) [][2]int { //Start and length of each piece left after wrapping at size
+	if start+length <= size {
+		return [][2]int{{start, length}}
+	}
+	return [][2]int{{start, size - start}, {0, start + length - size}}
+}
+
+func setRGBA5551(pix []uint8, p uint16) { //Widened like VRAM.Image, the mask bit goes to alpha
+	pix[0] = uint8(p<<3) | uint8(p>>2)&7
+	pix[1] = uint8(p>>5<<3) | uint8(p>>7)&7
+	pix[2] = uint8(p>>10<<3) | uint8(p>>12)&7
+	pix[3] = 0
+	if p&gpu.MASK_BIT != 0 {
+		pix[3] = 0xff
+	}
+}
+
+func rgba5551(pix []uint8) uint16 {
+	p := uint16(pix[0]>>3) | uint16(pix[1]>>3)<<5 | uint16(pix[2]>>3)<<10
+	if pix[3] >= 0x80 {
+		p |= gpu.MASK_BIT
+	}
+	return p
+}
+
+// Copies the display area to the window. 24 bit output isn't decoded.
+func (r *GLRenderer) Display(area gpu.DisplayArea){
+	r.Draw()
+
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, r.framebuffer)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, 0)
+	gl.Disable(gl.SCISSOR_TEST)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	if !area.Disabled && area.Width > 0 && area.Height > 0 {
+		width, height := r.window.GetSize()
+		top := int32(gpu.VRAM_HEIGHT) - int32(area.Y) //GL rows count from the bottom
+		gl.BlitFramebuffer(
+			int32(area.X), top-int32(area.Height), int32(area.X)+int32(area.Width), top,
+			0, 0, width, height,
+			gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	}
+	r.window.GLSwap()
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, r.framebuffer)
+	gl.Enable(gl.SCISSOR_TEST)
+}
+
+func (r *GLRenderer) Drop(){
+	gl.DeleteFramebuffers(1, &r.framebuffer)
+	gl.DeleteTextures(1, &r.frameTexture)
+	gl.DeleteTextures(1, &r.vramTexture)
+	gl.DeleteVertexArrays(1, &r.vao)
+	gl.DeleteShader(r.vertexShader)
+	gl.DeleteShader(r.fragmentShader)
+	gl.DeleteProgram(r.program)
+}
\ No newline at end of file
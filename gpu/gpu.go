@@ -16,6 +16,7 @@ type GPU struct {
     PreserveMaskedPixels    bool // preserve masked pixels
     Field                   Field // current field
     TextureDisable          bool // disable texture
+    TextureDisableAllowed   bool // GP1 0x09, lets texpage bit 11 disable texturing
     HRes                    HorizontalRes
     VRes                    VerticalRes
     VMode                   VideoMode
@@ -34,6 +35,8 @@ type GPU struct {
     DrawingAreaTop          uint16
     DrawingAreaRight        uint16
     DrawingAreaBottom       uint16
+    DrawingXOffset          int16
+    DrawingYOffset          int16
     DisplayVRAMXStart       uint16
     DisplayVRAMYStart       uint16
     DisplayHorizStart       uint16
@@ -44,7 +47,12 @@ type GPU struct {
 	Gp0WordsRemaining 	    uint32
 	Gp0CommandMethod 		func(*GPU)
     Gp0Mode                 Gp0Mode
+    PolyLine                PolyLine // Polyline being received
+    Upload                  VRAMTransfer // 0xA0 CPU to VRAM
+    Download                VRAMTransfer // 0xC0 VRAM to CPU
+    GpuRead                 uint32 // Last GPUREAD value
     Renderer                Renderer
+    Timing                  Timing // Scanline position
 }
 
 type TextureDepth uint8
@@ -112,8 +120,40 @@ type Gp0Mode uint8
 const (
     Command Gp0Mode = iota
     ImageLoad
+    PolyLineVertices // Polyline vertices until the terminator
 )
 
+// Opcode bits of the 0x40-0x5F line commands
+const (
+	LINE_SEMITRANSPARENT = 0x02
+	LINE_POLY            = 0x08
+	LINE_SHADED          = 0x10
+)
+
+// Opcode bits of the 0x60-0x7F rectangle commands, bits 3-4 are the size
+const (
+	RECT_RAW_TEXTURE     = 0x01
+	RECT_SEMITRANSPARENT = 0x02
+	RECT_TEXTURED        = 0x04
+)
+
+// Rectangle sizes by bits 3-4, 0x0 is variable
+var RECT_SIZES = [4]uint16{0, 1, 8, 16}
+
+// Polylines can be any length, so their vertices are drawn as they arrive
+type PolyLine struct {
+	Attr       Attributes
+	Last       Position
+	LastColour Colour
+	Colour     Colour //Colour word of the vertex being received
+	HasColour  bool
+	Vertices   uint32
+}
+
+// Any word with this pattern ends a polyline, once it has two vertices
+const POLYLINE_TERMINATOR_MASK uint32 = 0xF000F000
+const POLYLINE_TERMINATOR uint32 = 0x50005000
+
 // NewGPUInstance initializes a new GPU instance with default values.
 func (g GPU) New(r Renderer) GPU {
     return GPU{
@@ -127,6 +167,7 @@ func (g GPU) New(r Renderer) GPU {
         PreserveMaskedPixels: false,
         Field: Top,
         TextureDisable: false,
+        TextureDisableAllowed: false,
         HRes: NewHorizontalRes(0, 0),
         VRes: Y240Lines,
         VMode: NTSC,
@@ -176,11 +217,11 @@ func (g *GPU) Status() uint32 {
     r |= boolToUint32(g.DrawToDisplay) << 10
     r |= boolToUint32(g.ForceSetMaskBit) << 11
     r |= boolToUint32(g.PreserveMaskedPixels) << 12
-    r |= uint32(g.Field) << 13
+    r |= boolToUint32(!g.Interlaced || g.Field == Bottom) << 13
     // Bit 14: not supported
     r |= boolToUint32(g.TextureDisable) << 15
     r |= g.HRes.IntoStatus()
-    //r |= uint32(g.VRes) << 19
+    r |= uint32(g.VRes) << 19
     r |= uint32(g.VMode) << 20
     r |= uint32(g.DisplayDepth) << 21
     r |= boolToUint32(g.Interlaced) << 22
@@ -191,12 +232,11 @@ func (g *GPU) Status() uint32 {
     // Receive
     r |= 1 << 26
     // Send VRAM
-    r |= 1 << 27
+    r |= boolToUint32(g.Download.Active) << 27
     // Receive Block
     r |= 1 << 28
     r |= uint32(g.DmaDir) << 29
-    // 31 should change depending on the drawn line
-    r |= 0 << 31
+    r |= boolToUint32(g.OddLine()) << 31
 
     // DMA Request
     var dmaReq uint32
@@ -218,36 +258,49 @@ func (g *GPU) Status() uint32 {
 type Gp0Method func(*GPU, uint32)
 
 func (g *GPU) Gp0(val uint32) {
+    if g.Gp0Mode == PolyLineVertices {
+        g.Gp0PolyLineVertex(val)
+        return
+    }
+
     if g.Gp0WordsRemaining == 0 {
         opcode := (val >> 24) & 0xFF
 
         var method Gp0Method
         var len uint32
 
-        switch opcode {
-        case 0x00:
+        switch {
+        case opcode == 0x00:
             len, method = 1, Gp0NopWrapper
-        case 0x28:
-            len, method = 5, Gp0QuadMonoOpaqueWrapper
-        case 0x2C:
-            len, method = 4, Gp0QTBlendOpaqueWrapper
-        case 0x30:
-            len, method = 6, Gp0TriShadedOpaqueWrapper
-        case 0x38:
-            len, method = 8, Gp0QuadShadedOpaqueWrapper
-        case 0xA0:
+        case opcode == 0x01:
+            len, method = 1, Gp0ClearCacheWrapper
+        case opcode == 0x02:
+            len, method = 3, Gp0FillRectWrapper
+        case opcode >= 0x20 && opcode <= 0x3F:
+            len, method = PolygonWords(opcode), Gp0PolygonWrapper
+        case opcode >= 0x40 && opcode <= 0x5F && opcode&LINE_POLY != 0:
+            len, method = 2, Gp0PolyLineWrapper //Up to the first vertex
+        case opcode >= 0x40 && opcode <= 0x5F:
+            len, method = LineWords(opcode), Gp0LineWrapper
+        case opcode >= 0x60 && opcode <= 0x7F:
+            len, method = RectangleWords(opcode), Gp0RectangleWrapper
+        case opcode >= 0x80 && opcode <= 0x9F:
+            len, method = 4, Gp0VRAMCopyWrapper
+        case opcode >= 0xA0 && opcode <= 0xBF:
             len, method = 3, Gp0ImgLoadWrapper
-        case 0xE1:
+        case opcode >= 0xC0 && opcode <= 0xDF:
+            len, method = 3, Gp0ImgStoreWrapper
+        case opcode == 0xE1:
             len, method = 1, Gp0DrawModeWrapper
-        case 0xE2:
+        case opcode == 0xE2:
             len, method = 1, Gp0TexWindowWrapper
-        case 0xE3:
+        case opcode == 0xE3:
             len, method = 1, Gp0DrawAreaTLWrapper
-        case 0xE4:
+        case opcode == 0xE4:
             len, method = 1, Gp0DrawAreaBRWrapper
-        case 0xE5:
+        case opcode == 0xE5:
             len, method = 1, Gp0DrawOffsetWrapper
-        case 0xE6:
+        case opcode == 0xE6:
             len, method = 1, Gp0MaskBitSettingWrapper
         default:
             panic(fmt.Sprintf("Unhandled GP0 command: 0x%X", opcode))
@@ -270,7 +323,7 @@ func (g *GPU) Gp0(val uint32) {
             g.Gp0CommandMethod(g)
         }
     case ImageLoad:
-        ///Copy Pixel Data
+        g.Gp0ImgLoadWord(val)
         if g.Gp0WordsRemaining == 0 {
             g.Gp0Mode = Command
         }
@@ -280,114 +333,240 @@ func (g *GPU) Gp0(val uint32) {
 func (g *GPU) Gp0Nop() { //0x00
 }
 
-func (g *GPU) Gp0QuadMonoOpaque() { //0x28
-	positions := []Position{
-        PFromGP0(g.Gp0Command.Index(1)),
-        PFromGP0(g.Gp0Command.Index(2)),
-        PFromGP0(g.Gp0Command.Index(3)),
-        PFromGP0(g.Gp0Command.Index(4)),
-    }
+// Opcode bits of the 0x20-0x3F polygon commands
+const (
+	POLY_RAW_TEXTURE     = 0x01
+	POLY_SEMITRANSPARENT = 0x02
+	POLY_TEXTURED        = 0x04
+	POLY_QUAD            = 0x08
+	POLY_SHADED          = 0x10
+)
 
-    colours := []Colour{
-        CFromGP0(g.Gp0Command.Index(0)),
-        CFromGP0(g.Gp0Command.Index(0)),
-        CFromGP0(g.Gp0Command.Index(0)),
-        CFromGP0(g.Gp0Command.Index(0)),
-    }
+func PolygonWords(opcode uint32) uint32 { //Command word, then per vertex an optional colour, the position and an optional UV
+	vertices := uint32(3)
+	if opcode&POLY_QUAD != 0 {
+		vertices = 4
+	}
 
-    g.Renderer.PushQuad(positions, colours)
+	words := 1 + vertices
+	if opcode&POLY_TEXTURED != 0 {
+		words += vertices
+	}
+	if opcode&POLY_SHADED != 0 {
+		words += vertices - 1 //The first colour is in the command word
+	}
+	return words
 }
 
-func (g *GPU) Gp0QTBlendOpaque() { //0x2C, Will add textures
-    positions := []Position{
-        PFromGP0(g.Gp0Command.Index(1)),
-        PFromGP0(g.Gp0Command.Index(3)),
-        PFromGP0(g.Gp0Command.Index(5)),
-        PFromGP0(g.Gp0Command.Index(7)),
-    }
+func (g *GPU) Gp0Polygon(val uint32) { //0x20-0x3F
+	opcode := val >> 24
+	attr := Attributes{
+		Shaded:          opcode&POLY_SHADED != 0,
+		Textured:        opcode&POLY_TEXTURED != 0,
+		SemiTransparent: opcode&POLY_SEMITRANSPARENT != 0,
+	}
+	attr.RawTexture = attr.Textured && opcode&POLY_RAW_TEXTURE != 0
 
-    colours := []Colour{
-        {0x80, 0x00, 0x00},
-        {0x80, 0x00, 0x00},
-        {0x80, 0x00, 0x00},
-        {0x80, 0x00, 0x00},
-    }
+	vertices := 3
+	if opcode&POLY_QUAD != 0 {
+		vertices = 4
+	}
+
+	positions := make([]Position, vertices)
+	colours := make([]Colour, vertices)
+	var uvs []TexCoord
+	if attr.Textured {
+		uvs = make([]TexCoord, vertices)
+	}
 
-    g.Renderer.PushQuad(positions, colours)
+	colour := CFromGP0(g.Gp0Command.Index(0))
+	word := 1
+	for i := 0; i < vertices; i++ {
+		if attr.Shaded && i > 0 {
+			colour = CFromGP0(g.Gp0Command.Index(word))
+			word++
+		}
+		colours[i] = colour
+
+		positions[i] = PFromGP0(g.Gp0Command.Index(word))
+		word++
+
+		if attr.Textured {
+			uv := g.Gp0Command.Index(word)
+			word++
+			uvs[i] = TFromGP0(uv)
+
+			switch i {
+			case 0: //Palette in the upper half
+				attr.ClutX, attr.ClutY = ClutFromGP0(uv >> 16)
+			case 1: //Texture page, which also lands in the status register
+				g.SetTexPage(uv >> 16)
+			}
+		}
+	}
+
+	g.drawModeAttributes(&attr)
+
+	if vertices == 4 {
+		g.Renderer.PushQuad(positions, colours, uvs, attr)
+	} else {
+		g.Renderer.PushTriangle(positions, colours, uvs, attr)
+	}
 }
 
-func (g *GPU) Gp0TriShadedOpaque() { //0x30
-    positions := []Position{
-        PFromGP0(g.Gp0Command.Index(1)),
-        PFromGP0(g.Gp0Command.Index(3)),
-        PFromGP0(g.Gp0Command.Index(5)),
-    }
+func ClutFromGP0(val uint32) (uint16, uint16) { //Palette position, X in 16 pixel steps
+	return uint16(val&0x3F) * 16, uint16((val >> 6) & 0x1FF)
+}
 
-    colours := []Colour{
-        CFromGP0(g.Gp0Command.Index(0)),
-        CFromGP0(g.Gp0Command.Index(2)),
-        CFromGP0(g.Gp0Command.Index(4)),
-    }
+func (g *GPU) drawModeAttributes(attr *Attributes) { //Texture page, window and blending from the draw mode
+	attr.Blend = BlendMode(g.SemiTransparency)
+	attr.PageX = uint16(g.PageBaseX) * 64
+	attr.PageY = uint16(g.PageBaseY) * 256
+	attr.Depth = g.TextureDepth
+	attr.WindowMaskX, attr.WindowMaskY = g.TextureWindowXMask, g.TextureWindowYMask
+	attr.WindowOffsetX, attr.WindowOffsetY = g.TextureWindowXOffset, g.TextureWindowYOffset
 
-    g.Renderer.PushTriangle(positions, colours)
+	if g.TextureDisable { //Drawn with the plain colour
+		attr.Textured = false
+		attr.RawTexture = false
+	}
 }
 
-func (g *GPU) Gp0QuadShadedOpaque() { //0x38
-    positions := []Position{
-        PFromGP0(g.Gp0Command.Index(1)),
-        PFromGP0(g.Gp0Command.Index(3)),
-        PFromGP0(g.Gp0Command.Index(5)),
-        PFromGP0(g.Gp0Command.Index(7)),
-    }
+func LineWords(opcode uint32) uint32 {
+	if opcode&LINE_SHADED != 0 {
+		return 4
+	}
+	return 3
+}
 
-    colours := []Colour{
-        CFromGP0(g.Gp0Command.Index(0)),
-        CFromGP0(g.Gp0Command.Index(2)),
-        CFromGP0(g.Gp0Command.Index(4)),
-        CFromGP0(g.Gp0Command.Index(6)),
-    }
+func (g *GPU) lineAttributes(opcode uint32) Attributes {
+	return Attributes{
+		Shaded:          opcode&LINE_SHADED != 0,
+		SemiTransparent: opcode&LINE_SEMITRANSPARENT != 0,
+		Blend:           BlendMode(g.SemiTransparency),
+	}
+}
+
+func (g *GPU) Gp0Line(val uint32) { //0x40-0x5F without the polyline bit
+	attr := g.lineAttributes(val >> 24)
+
+	c0 := CFromGP0(g.Gp0Command.Index(0))
+	c1 := c0
+	p0 := PFromGP0(g.Gp0Command.Index(1))
+	var p1 Position
+	if attr.Shaded {
+		c1 = CFromGP0(g.Gp0Command.Index(2))
+		p1 = PFromGP0(g.Gp0Command.Index(3))
+	} else {
+		p1 = PFromGP0(g.Gp0Command.Index(2))
+	}
 
-    g.Renderer.PushQuad(positions, colours)
+	g.Renderer.PushLine([]Position{p0, p1}, []Colour{c0, c1}, attr)
 }
 
-func (g *GPU) Gp0ImgLoad(val uint32) { //0xA0
-    res := g.Gp0Command.Index(2)
-    w := (res & 0xFFFF)
-    h := res >> 16
-    imgsize := w * h
-    imgsize = (imgsize + 1) & ^uint32(1)
-    g.Gp0WordsRemaining = imgsize/2
-    g.Gp0Mode = ImageLoad
+func (g *GPU) Gp0PolyLine(val uint32) { //0x48-0x5F with the polyline bit, the first vertex
+	g.PolyLine = PolyLine{
+		Attr:       g.lineAttributes(val >> 24),
+		Last:       PFromGP0(g.Gp0Command.Index(1)),
+		LastColour: CFromGP0(g.Gp0Command.Index(0)),
+		Vertices:   1,
+	}
+	g.Gp0Mode = PolyLineVertices
 }
 
-func (g *GPU) Gp0ImgStore(val uint32) { //0xC0
-    res := g.Gp0Command.Index(2)
-    w := (res & 0xFFFF)
-    h := res >> 16
-    fmt.Println(w, h)
+func (g *GPU) Gp0PolyLineVertex(val uint32) {
+	l := &g.PolyLine
+	if l.Vertices >= 2 && !l.HasColour && val&POLYLINE_TERMINATOR_MASK == POLYLINE_TERMINATOR {
+		g.Gp0Mode = Command
+		return
+	}
+
+	if l.Attr.Shaded && !l.HasColour {
+		l.Colour = CFromGP0(val)
+		l.HasColour = true
+		return
+	}
+
+	colour := l.LastColour
+	if l.Attr.Shaded {
+		colour = l.Colour
+		l.HasColour = false
+	}
+	position := PFromGP0(val)
+
+	g.Renderer.PushLine([]Position{l.Last, position}, []Colour{l.LastColour, colour}, l.Attr)
+	l.Last, l.LastColour = position, colour
+	l.Vertices++
 }
 
-func (g *GPU) Gp0DrawMode(val uint32) { //0xE1
-    g.PageBaseX = uint8(val & 0xF)
-    g.PageBaseY = uint8((val >> 4) & 1)
-    g.SemiTransparency = uint8((val >> 5) & 3)
-
-    switch (val >> 7) & 3 {
-    case 0:
-        g.TextureDepth = T4Bit
-    case 1:
-        g.TextureDepth = T8Bit
-    case 2:
-        g.TextureDepth = T15Bit
-    default:
-        panic(fmt.Sprintf("Invalid texture depth: %d", (val >> 7) & 3))
-    }
+func RectangleWords(opcode uint32) uint32 { //Command word, position, optional UV and optional size
+	words := uint32(2)
+	if opcode&RECT_TEXTURED != 0 {
+		words++
+	}
+	if (opcode>>3)&3 == 0 {
+		words++
+	}
+	return words
+}
+
+func (g *GPU) Gp0Rectangle(val uint32) { //0x60-0x7F
+	opcode := val >> 24
+	attr := Attributes{
+		Textured:        opcode&RECT_TEXTURED != 0,
+		SemiTransparent: opcode&RECT_SEMITRANSPARENT != 0,
+		FlipX:           g.RectangleTextureXFlip,
+		FlipY:           g.RectangleTextureYFlip,
+	}
+	attr.RawTexture = attr.Textured && opcode&RECT_RAW_TEXTURE != 0
+
+	colour := CFromGP0(g.Gp0Command.Index(0))
+	position := PFromGP0(g.Gp0Command.Index(1))
+	word := 2
+
+	var uv TexCoord
+	if attr.Textured {
+		w := g.Gp0Command.Index(word)
+		word++
+		uv = TFromGP0(w)
+		attr.ClutX, attr.ClutY = ClutFromGP0(w >> 16)
+	}
+
+	width, height := RECT_SIZES[(opcode>>3)&3], RECT_SIZES[(opcode>>3)&3]
+	if width == 0 {
+		size := g.Gp0Command.Index(word)
+		width = uint16(size & 0x3FF)
+		height = uint16((size >> 16) & 0x1FF)
+	}
+
+	g.drawModeAttributes(&attr)
+	g.Renderer.PushRectangle(position, width, height, colour, uv, attr)
+}
+
+func (g *GPU) SetTexPage(val uint32) { //Texpage attribute of textured polygons, same layout as the low bits of 0xE1
+	g.PageBaseX = uint8(val & 0xF)
+	g.PageBaseY = uint8((val >> 4) & 1)
+	g.SemiTransparency = uint8((val >> 5) & 3)
+
+	switch (val >> 7) & 3 {
+	case 0:
+		g.TextureDepth = T4Bit
+	case 1:
+		g.TextureDepth = T8Bit
+	default: //3 is reserved and behaves like 15 bit
+		g.TextureDepth = T15Bit
+	}
+
+	g.TextureDisable = g.TextureDisableAllowed && (val >> 11) & 1 != 0
+}
 
+func (g *GPU) Gp0DrawMode(val uint32) { //0xE1
+    g.SetTexPage(val)
     g.Dithering = (val >> 9) & 1 != 0
     g.DrawToDisplay = (val >> 10) & 1 != 0
-    g.TextureDisable = (val >> 11) & 1 != 0
     g.RectangleTextureXFlip = (val >> 12) & 1 != 0
     g.RectangleTextureYFlip = (val >> 13) & 1 != 0
+    g.Renderer.SetDithering(g.Dithering)
 }
 
 func (g *GPU) Gp0TexWindow(val uint32){ //0xE2
@@ -400,11 +579,17 @@ func (g *GPU) Gp0TexWindow(val uint32){ //0xE2
 func (g *GPU) Gp0DrawAreaTL(val uint32){ //0xE3
 	g.DrawingAreaLeft = uint16(val & 0x3FF)
 	g.DrawingAreaTop = uint16((val >> 10) & 0x3FF)
+	g.updateDrawingArea()
 }
 
 func (g *GPU) Gp0DrawAreaBR(val uint32){ //0xE4
 	g.DrawingAreaRight = uint16(val & 0x3FF)
 	g.DrawingAreaBottom = uint16((val >> 10) & 0x3FF)
+	g.updateDrawingArea()
+}
+
+func (g *GPU) updateDrawingArea() {
+	g.Renderer.SetDrawingArea(g.DrawingAreaLeft, g.DrawingAreaTop, g.DrawingAreaRight, g.DrawingAreaBottom)
 }
 
 func (g *GPU) Gp0DrawOffset(val uint32){ //0xE5
@@ -412,15 +597,15 @@ func (g *GPU) Gp0DrawOffset(val uint32){ //0xE5
 	y := int16((val >> 11) & 0x7FF)
 
 	// Sign extend to 16 bits
-	XOffset := int16(x << 5) >> 5
-	YOffset := int16(y << 5) >> 5
-    g.Renderer.DrawOffset(XOffset, YOffset)
-    g.Renderer.Display()
+	g.DrawingXOffset = int16(x << 5) >> 5
+	g.DrawingYOffset = int16(y << 5) >> 5
+    g.Renderer.DrawOffset(g.DrawingXOffset, g.DrawingYOffset)
 }
 
 func (g *GPU) Gp0MaskBitSetting(val uint32){ //0xE6
 	g.ForceSetMaskBit = (val & 1) != 0
 	g.PreserveMaskedPixels = (val & 2) != 0
+	g.Renderer.SetMaskBit(g.ForceSetMaskBit, g.PreserveMaskedPixels)
 }
 
 func (g *GPU) Gp0ClearCache() {
@@ -428,13 +613,33 @@ func (g *GPU) Gp0ClearCache() {
 }
 
 func (g *GPU) Gp1 (val uint32) {
-	opcode := val & 0xFF
-	switch opcode {
-	case 0x00:
+	opcode := (val >> 24) & 0x3F //0x40-0xFF mirror 0x00-0x3F
+	switch {
+	case opcode == 0x00:
 		// Reset GPU
 		g.Gp1Reset()
+	case opcode == 0x01:
+		g.Gp1ResetCommBuffer(val)
+	case opcode == 0x02:
+		g.Gp1AcknowledgeIRQ()
+	case opcode == 0x03:
+		g.Gp1DisplayEnable(val)
+	case opcode == 0x04:
+		g.Gp1DMADir(val)
+	case opcode == 0x05:
+		g.Gp1DisplayVRAMStart(val)
+	case opcode == 0x06:
+		g.Gp1DisplayHRange(val)
+	case opcode == 0x07:
+		g.Gp1DisplayVRange(val)
+	case opcode == 0x08:
+		g.Gp1DisplayMode(val)
+	case opcode == 0x09:
+		g.Gp1TextureDisable(val)
+	case opcode >= 0x10 && opcode <= 0x1F:
+		g.Gp1GPUInfo(val)
 	default:
-		panic(fmt.Sprintf("Invalid GP1 opcode: 0x%X", opcode))
+		//0x0A-0x0F and 0x20-0x3F do nothing on retail GPUs
 	}
 }
 
@@ -458,6 +663,8 @@ func (g *GPU) Gp1Reset() {
 	g.DrawingAreaTop = 0
 	g.DrawingAreaRight = 0
 	g.DrawingAreaBottom = 0
+	g.DrawingXOffset = 0
+	g.DrawingYOffset = 0
 	g.ForceSetMaskBit = false
 	g.PreserveMaskedPixels = false
 
@@ -476,8 +683,14 @@ func (g *GPU) Gp1Reset() {
 	g.DisplayLineStart = 0x10
 	g.DisplayLineEnd = 0x100
 	g.DisplayDepth = D15Bit
+
+	g.Renderer.SetDithering(false)
+	g.Renderer.SetMaskBit(false, false)
+	g.Renderer.DrawOffset(0, 0)
+	g.updateDrawingArea()
 	
 	//clear fifo and gpu
+	g.Gp1ResetCommBuffer(0)
 }
 
 func (g *GPU) Gp1DisplayMode(val uint32){ //0x80
@@ -502,9 +715,9 @@ func (g *GPU) Gp1DisplayMode(val uint32){ //0x80
 
 	switch (val & 0x10) != 0 {
 	case true:
-		g.DisplayDepth = D15Bit
-	case false:
 		g.DisplayDepth = D24Bit
+	case false:
+		g.DisplayDepth = D15Bit
 	}
 
 	g.Interlaced = (val & 0x20) != 0
@@ -518,6 +731,11 @@ func (g *GPU) Gp1ResetCommBuffer(val uint32){ //0x01
     g.Gp0Command.Clear()
     g.Gp0WordsRemaining = 0
     g.Gp0Mode = Command
+    if g.Upload.Active {
+        g.uploadWritten()
+    }
+    g.Upload.Active = false
+    g.Download.Active = false
 }
 
 func (g *GPU) Gp1AcknowledgeIRQ(){ //0x02
@@ -558,34 +776,70 @@ func (g *GPU) Gp1DisplayVRange(val uint32){ //0x07
 	g.DisplayLineEnd = uint16((val >> 10) & 0x3FF)
 }
 
-func (g *GPU) Read() uint32{
-	return 0
+func (g *GPU) Gp1TextureDisable(val uint32){ //0x09
+	g.TextureDisableAllowed = (val & 1) != 0
+}
+
+func (g *GPU) Gp1GPUInfo(val uint32){ //0x10-0x1F, answer goes to GPUREAD
+	switch val & 0xF {
+	case 0x2:
+		g.GpuRead = uint32(g.TextureWindowXMask) |
+			uint32(g.TextureWindowYMask) << 5 |
+			uint32(g.TextureWindowXOffset) << 10 |
+			uint32(g.TextureWindowYOffset) << 15
+	case 0x3:
+		g.GpuRead = uint32(g.DrawingAreaLeft) | uint32(g.DrawingAreaTop) << 10
+	case 0x4:
+		g.GpuRead = uint32(g.DrawingAreaRight) | uint32(g.DrawingAreaBottom) << 10
+	case 0x5:
+		g.GpuRead = uint32(uint16(g.DrawingXOffset) & 0x7FF) | uint32(uint16(g.DrawingYOffset) & 0x7FF) << 11
+	case 0x7: //GPU version, 2 for the 208 pin GPU
+		g.GpuRead = 2
+	case 0x8:
+		g.GpuRead = 0
+	default: //Leaves the old value in GPUREAD
+	}
 }
 
 func Gp0NopWrapper(g *GPU, val uint32) {
     g.Gp0Nop()
 }
-func Gp0QuadMonoOpaqueWrapper(g *GPU, val uint32) {
-    // Assuming Gp0QuadMonoOpaque is a method that requires the val parameter
-    g.Gp0QuadMonoOpaque()
+func Gp0PolygonWrapper(g *GPU, val uint32) {
+    g.Gp0Polygon(val)
 }
 
-func Gp0QTBlendOpaqueWrapper(g *GPU, val uint32) {
-    g.Gp0QTBlendOpaque()
+func Gp0LineWrapper(g *GPU, val uint32) {
+    g.Gp0Line(val)
 }
 
-func Gp0TriShadedOpaqueWrapper(g *GPU, val uint32) {
-    g.Gp0TriShadedOpaque()
+func Gp0PolyLineWrapper(g *GPU, val uint32) {
+    g.Gp0PolyLine(val)
 }
 
-func Gp0QuadShadedOpaqueWrapper(g *GPU, val uint32) {
-    g.Gp0QuadShadedOpaque()
+func Gp0RectangleWrapper(g *GPU, val uint32) {
+    g.Gp0Rectangle(val)
 }
 
 func Gp0ImgLoadWrapper(g *GPU, val uint32) {
     g.Gp0ImgLoad(val)
 }
 
+func Gp0ImgStoreWrapper(g *GPU, val uint32) {
+    g.Gp0ImgStore(val)
+}
+
+func Gp0VRAMCopyWrapper(g *GPU, val uint32) {
+    g.Gp0VRAMCopy(val)
+}
+
+func Gp0FillRectWrapper(g *GPU, val uint32) {
+    g.Gp0FillRect(val)
+}
+
+func Gp0ClearCacheWrapper(g *GPU, val uint32) {
+    g.Gp0ClearCache()
+}
+
 func Gp0DrawModeWrapper(g *GPU, val uint32) {
     g.Gp0DrawMode(val)
 }
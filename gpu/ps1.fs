@@ -1,8 +0,0 @@
-#version 460 core
-
-in vec3 color;
-out vec4 fragColor;
-
-void main(){
-    fragColor = vec4(color, 1.0);
-}
\ No newline at end of file
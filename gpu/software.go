@@ -0,0 +1,359 @@
+package gpu
+
+import "image"
+
+// Pure Go rasterizer drawing straight into VRAM, the accuracy reference
+type SoftwareRenderer struct {
+	vram       *VRAM
+	offsetX    int32
+	offsetY    int32
+	areaLeft   int32 //Drawing area, inclusive
+	areaTop    int32
+	areaRight  int32
+	areaBottom int32
+	dither     bool
+	setMask    bool //Force bit 15 on drawn pixels
+	checkMask  bool //Leave pixels with bit 15 alone
+	display    DisplayArea
+}
+
+// 4x4 ordered dither, added to 8 bit colours before truncating to 5 bits
+var DITHER_MATRIX = [4][4]int32{
+	{-4, 0, -3, 1},
+	{2, -2, 3, -1},
+	{-3, 1, -4, 0},
+	{3, -1, 2, -2},
+}
+
+const (
+	MAX_PRIMITIVE_WIDTH  = 1023 //Larger polygons are dropped by the GPU
+	MAX_PRIMITIVE_HEIGHT = 511
+)
+
+func NewSoftware() *SoftwareRenderer {
+	return &SoftwareRenderer{vram: NewVRAM()}
+}
+
+func (r *SoftwareRenderer) VRAM() *VRAM {
+	return r.vram
+}
+
+func (r *SoftwareRenderer) SyncVRAM() { //Drawing goes straight to VRAM
+}
+
+func (r *SoftwareRenderer) VRAMWritten(x uint16, y uint16, width uint16, height uint16) {
+}
+
+func (r *SoftwareRenderer) PushTriangle(positions []Position, colours []Colour, uvs []TexCoord, attr Attributes) {
+	r.triangle(r.vertices(positions, colours, uvs, 0, 1, 2), attr)
+}
+
+func (r *SoftwareRenderer) PushQuad(positions []Position, colours []Colour, uvs []TexCoord, attr Attributes) { //Two triangles sharing the 1-2 edge
+	r.triangle(r.vertices(positions, colours, uvs, 0, 1, 2), attr)
+	r.triangle(r.vertices(positions, colours, uvs, 1, 2, 3), attr)
+}
+
+func (r *SoftwareRenderer) vertices(positions []Position, colours []Colour, uvs []TexCoord, indices ...int) [3]vertex {
+	var v [3]vertex
+	for i, n := range indices {
+		v[i] = vertex{
+			x: signExtend11(int32(positions[n].X) + r.offsetX),
+			y: signExtend11(int32(positions[n].Y) + r.offsetY),
+			r: int32(colours[n].R),
+			g: int32(colours[n].G),
+			b: int32(colours[n].B),
+		}
+		if uvs != nil {
+			v[i].u, v[i].v = int32(uvs[n].U), int32(uvs[n].V)
+		}
+	}
+	return v
+}
+
+// Lines step one pixel at a time along the major axis in 32.32 fixed point, like the GPU
+func (r *SoftwareRenderer) PushLine(positions []Position, colours []Colour, attr Attributes) {
+	var v [2]vertex
+	for i := range v {
+		v[i] = vertex{
+			x: signExtend11(int32(positions[i].X) + r.offsetX),
+			y: signExtend11(int32(positions[i].Y) + r.offsetY),
+			r: int32(colours[i].R),
+			g: int32(colours[i].G),
+			b: int32(colours[i].B),
+		}
+	}
+
+	dx, dy := abs32(v[1].x-v[0].x), abs32(v[1].y-v[0].y)
+	if dx > MAX_PRIMITIVE_WIDTH || dy > MAX_PRIMITIVE_HEIGHT {
+		return
+	}
+	k := max(dx, dy)
+	if v[0].x >= v[1].x && k > 0 { //Always drawn left to right
+		v[0], v[1] = v[1], v[0]
+	}
+
+	var stepX, stepY int64
+	var stepC [3]int64
+	if k > 0 {
+		stepX = lineStep(int64(v[1].x-v[0].x)<<32, k)
+		stepY = lineStep(int64(v[1].y-v[0].y)<<32, k)
+		stepC = [3]int64{
+			lineStep(int64(v[1].r-v[0].r)<<GRADIENT_FRACTION, k),
+			lineStep(int64(v[1].g-v[0].g)<<GRADIENT_FRACTION, k),
+			lineStep(int64(v[1].b-v[0].b)<<GRADIENT_FRACTION, k),
+		}
+	}
+
+	x := (int64(v[0].x)<<32 | 1<<31) - 1024
+	y := int64(v[0].y)<<32 | 1<<31
+	if stepY < 0 {
+		y -= 1024
+	}
+	c := [3]int64{
+		int64(v[0].r)<<GRADIENT_FRACTION | 1<<(GRADIENT_FRACTION-1),
+		int64(v[0].g)<<GRADIENT_FRACTION | 1<<(GRADIENT_FRACTION-1),
+		int64(v[0].b)<<GRADIENT_FRACTION | 1<<(GRADIENT_FRACTION-1),
+	}
+	dither := r.dither && attr.Shaded
+
+	for i := int32(0); i <= k; i++ {
+		px, py := signExtend11(int32(x>>32)), signExtend11(int32(y>>32))
+		if r.inArea(px, py) {
+			cr := clamp8(int32(c[0] >> GRADIENT_FRACTION))
+			cg := clamp8(int32(c[1] >> GRADIENT_FRACTION))
+			cb := clamp8(int32(c[2] >> GRADIENT_FRACTION))
+			r.fragment(px, py, cr, cg, cb, TexCoord{}, attr, dither)
+		}
+
+		x += stepX
+		y += stepY
+		for j := range c {
+			c[j] += stepC[j]
+		}
+	}
+}
+
+func lineStep(delta int64, k int32) int64 { //Rounded away from zero
+	if delta < 0 {
+		delta -= int64(k) - 1
+	} else if delta > 0 {
+		delta += int64(k) - 1
+	}
+	return delta / int64(k)
+}
+
+// Rectangles aren't split in triangles, texels step one per pixel and they're never dithered
+func (r *SoftwareRenderer) PushRectangle(position Position, width uint16, height uint16, colour Colour, uv TexCoord, attr Attributes) {
+	x0 := signExtend11(int32(position.X) + r.offsetX)
+	y0 := signExtend11(int32(position.Y) + r.offsetY)
+	cr, cg, cb := int32(colour.R), int32(colour.G), int32(colour.B)
+
+	du, dv := uint8(1), uint8(1)
+	if attr.FlipX {
+		du = 0xff
+	}
+	if attr.FlipY {
+		dv = 0xff
+	}
+
+	left, right := max(x0, r.areaLeft), min(x0+int32(width)-1, r.areaRight)
+	top, bottom := max(y0, r.areaTop), min(y0+int32(height)-1, r.areaBottom)
+
+	for y := top; y <= bottom; y++ {
+		v := uv.V + uint8(y-y0)*dv
+		for x := left; x <= right; x++ {
+			u := uv.U + uint8(x-x0)*du
+			r.fragment(x, y, cr, cg, cb, TexCoord{U: u, V: v}, attr, false)
+		}
+	}
+}
+
+func (r *SoftwareRenderer) DrawOffset(x int16, y int16) {
+	r.offsetX = int32(x)
+	r.offsetY = int32(y)
+}
+
+func (r *SoftwareRenderer) SetDrawingArea(left uint16, top uint16, right uint16, bottom uint16) {
+	r.areaLeft = int32(left)
+	r.areaTop = int32(top)
+	r.areaRight = int32(right)
+	r.areaBottom = int32(bottom)
+}
+
+func (r *SoftwareRenderer) SetDithering(enabled bool) {
+	r.dither = enabled
+}
+
+func (r *SoftwareRenderer) SetMaskBit(set bool, check bool) {
+	r.setMask = set
+	r.checkMask = check
+}
+
+func (r *SoftwareRenderer) Display(area DisplayArea) { //Everything is already in VRAM
+	r.display = area
+}
+
+func (r *SoftwareRenderer) Frame() *image.RGBA { //Last frame handed over, as it would be shown
+	return r.vram.DisplayImage(r.display)
+}
+
+func (r *SoftwareRenderer) Drop() {
+}
+
+type vertex struct {
+	x, y    int32
+	r, g, b int32
+	u, v    int32
+}
+
+func (r *SoftwareRenderer) triangle(v [3]vertex, attr Attributes) {
+	area := edge(v[0], v[1], v[2].x, v[2].y)
+	if area == 0 {
+		return
+	}
+	if area < 0 { //Make the winding consistent, the GPU draws both
+		v[1], v[2] = v[2], v[1]
+		area = -area
+	}
+
+	minX, maxX := min3(v[0].x, v[1].x, v[2].x), max3(v[0].x, v[1].x, v[2].x)
+	minY, maxY := min3(v[0].y, v[1].y, v[2].y), max3(v[0].y, v[1].y, v[2].y)
+	if maxX-minX > MAX_PRIMITIVE_WIDTH || maxY-minY > MAX_PRIMITIVE_HEIGHT {
+		return
+	}
+
+	minX, maxX = max(minX, r.areaLeft), min(maxX, r.areaRight)
+	minY, maxY = max(minY, r.areaTop), min(maxY, r.areaBottom)
+
+	shade := newGradient(v, area)
+	dither := r.dither && (attr.Shaded || (attr.Textured && !attr.RawTexture))
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			if !inside(v[1], v[2], x, y) || !inside(v[2], v[0], x, y) || !inside(v[0], v[1], x, y) {
+				continue
+			}
+
+			c := shade.at(x, y)
+			uv := TexCoord{U: uint8(c[3]), V: uint8(c[4])}
+			r.fragment(x, y, clamp8(c[0]), clamp8(c[1]), clamp8(c[2]), uv, attr, dither)
+		}
+	}
+}
+
+func (r *SoftwareRenderer) inArea(x int32, y int32) bool {
+	return x >= r.areaLeft && x <= r.areaRight && y >= r.areaTop && y <= r.areaBottom
+}
+
+// Every pixel of every primitive ends up here, 8 bit colour to 15 bit VRAM
+func (r *SoftwareRenderer) fragment(x int32, y int32, cr int32, cg int32, cb int32, uv TexCoord, attr Attributes, dither bool) {
+	back := r.vram.Get(x, y)
+	if r.checkMask && back&MASK_BIT != 0 {
+		return
+	}
+
+	semi := attr.SemiTransparent
+	mask := uint16(0)
+	if attr.Textured {
+		texel := r.vram.Texel(uv, &attr)
+		if texel == TRANSPARENT_TEXEL {
+			return
+		}
+		cr, cg, cb = Modulate(texel, cr, cg, cb, attr.RawTexture)
+		semi = semi && texel&MASK_BIT != 0 //Only texels with bit 15 blend
+		mask = texel & MASK_BIT
+	}
+
+	if dither {
+		d := DITHER_MATRIX[y&3][x&3]
+		cr, cg, cb = clamp8(cr+d), clamp8(cg+d), clamp8(cb+d)
+	}
+	pixel := uint16(cr>>3) | uint16(cg>>3)<<5 | uint16(cb>>3)<<10
+
+	if semi {
+		pixel = Blend(back, pixel, attr.Blend)
+	}
+	if r.setMask {
+		mask = MASK_BIT
+	}
+	r.vram.Set(x, y, pixel|mask)
+}
+
+func edge(a vertex, b vertex, x int32, y int32) int64 { //Positive when (x, y) is on the inner side of a->b
+	return int64(b.x-a.x)*int64(y-a.y) - int64(b.y-a.y)*int64(x-a.x)
+}
+
+func inside(a vertex, b vertex, x int32, y int32) bool { //Top-left rule: right and bottom edges aren't drawn
+	w := edge(a, b, x, y)
+	if w != 0 {
+		return w > 0
+	}
+	dx, dy := b.x-a.x, b.y-a.y
+	return dy < 0 || (dy == 0 && dx > 0)
+}
+
+// Gouraud colour and texture coordinates as planes over the triangle, stepped in 12 bit fixed point like the GPU
+type gradient struct {
+	x0, y0 int32
+	base   [5]int64
+	dx, dy [5]int64
+}
+
+const GRADIENT_FRACTION = 12
+
+func newGradient(v [3]vertex, area int64) gradient {
+	g := gradient{x0: v[0].x, y0: v[0].y}
+	channels := [5][3]int32{
+		{v[0].r, v[1].r, v[2].r},
+		{v[0].g, v[1].g, v[2].g},
+		{v[0].b, v[1].b, v[2].b},
+		{v[0].u, v[1].u, v[2].u},
+		{v[0].v, v[1].v, v[2].v},
+	}
+
+	for i, c := range channels {
+		d1, d2 := int64(c[1]-c[0]), int64(c[2]-c[0])
+		g.dx[i] = ((d1*int64(v[2].y-v[0].y) - d2*int64(v[1].y-v[0].y)) << GRADIENT_FRACTION) / area
+		g.dy[i] = ((d2*int64(v[1].x-v[0].x) - d1*int64(v[2].x-v[0].x)) << GRADIENT_FRACTION) / area
+		g.base[i] = int64(c[0])<<GRADIENT_FRACTION + 1<<(GRADIENT_FRACTION-1)
+	}
+	return g
+}
+
+func (g *gradient) at(x int32, y int32) [5]int32 { //R, G, B, U, V, unclamped
+	var c [5]int32
+	for i := range c {
+		v := g.base[i] + g.dx[i]*int64(x-g.x0) + g.dy[i]*int64(y-g.y0)
+		c[i] = int32(v >> GRADIENT_FRACTION)
+	}
+	return c
+}
+
+func signExtend11(v int32) int32 { //Vertex coordinates are 11 bit signed
+	return (v << 21) >> 21
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clamp8(v int32) int32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 0xff {
+		return 0xff
+	}
+	return v
+}
+
+func min3(a, b, c int32) int32 {
+	return min(a, min(b, c))
+}
+
+func max3(a, b, c int32) int32 {
+	return max(a, max(b, c))
+}
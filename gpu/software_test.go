@@ -0,0 +1,202 @@
+package gpu
+
+import (
+	"testing"
+)
+
+func newTestRenderer() *SoftwareRenderer {
+	r := NewSoftware()
+	r.SetDrawingArea(0, 0, VRAM_WIDTH-1, VRAM_HEIGHT-1)
+	return r
+}
+
+func TestTopLeftRule(t *testing.T) {
+	v := [3]vertex{{x: 0, y: 0}, {x: 4, y: 0}, {x: 0, y: 4}} //Wound the way triangle leaves it
+	for _, c := range []struct {
+		name string
+		x, y int32
+		want bool
+	}{
+		{"top left corner", 0, 0, true},
+		{"top edge", 3, 0, true},
+		{"left edge", 0, 3, true},
+		{"interior", 1, 1, true},
+		{"diagonal edge", 2, 2, false},
+		{"right corner", 4, 0, false},
+		{"bottom corner", 0, 4, false},
+		{"outside", 3, 3, false},
+		{"above", 1, -1, false},
+	} {
+		got := inside(v[1], v[2], c.x, c.y) && inside(v[2], v[0], c.x, c.y) && inside(v[0], v[1], c.x, c.y)
+		if got != c.want {
+			t.Errorf("%s (%d, %d): inside = %v", c.name, c.x, c.y, got)
+		}
+	}
+}
+
+func TestQuadCoverage(t *testing.T) { //Shared edges are drawn once, right and bottom edges not at all
+	r := newTestRenderer()
+	positions := []Position{{0, 0}, {4, 0}, {0, 4}, {4, 4}}
+	colour := Colour{R: 0x40}
+	colours := []Colour{colour, colour, colour, colour}
+	r.PushQuad(positions, colours, nil, Attributes{SemiTransparent: true, Blend: BlendAdd})
+
+	for y := int32(0); y <= 5; y++ {
+		for x := int32(0); x <= 5; x++ {
+			want := uint16(0)
+			if x < 4 && y < 4 {
+				want = 0x08 //Once, drawing twice would add up to 0x10
+			}
+			if got := r.vram.Get(x, y); got != want {
+				t.Errorf("(%d, %d) = 0x%04x, want 0x%04x", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestGradient(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		v    [3]vertex
+		x, y int32
+		want [5]int32
+	}{
+		{
+			name: "red along x",
+			v:    [3]vertex{{x: 0, y: 0}, {x: 16, y: 0, r: 128}, {x: 0, y: 16}},
+			x:    4, y: 0,
+			want: [5]int32{32, 0, 0, 0, 0},
+		},
+		{
+			name: "red along x, further down",
+			v:    [3]vertex{{x: 0, y: 0}, {x: 16, y: 0, r: 128}, {x: 0, y: 16}},
+			x:    15, y: 3,
+			want: [5]int32{120, 0, 0, 0, 0},
+		},
+		{
+			name: "green along y, blue flat",
+			v:    [3]vertex{{x: 0, y: 0, b: 100}, {x: 16, y: 0, b: 100}, {x: 0, y: 16, g: 160, b: 100}},
+			x:    0, y: 5,
+			want: [5]int32{0, 50, 100, 0, 0},
+		},
+		{
+			name: "u rounds from the pixel centre",
+			v:    [3]vertex{{x: 0, y: 0}, {x: 16, y: 0, u: 15}, {x: 0, y: 16, v: 16}},
+			x:    15, y: 1,
+			want: [5]int32{0, 0, 0, 14, 1}, //14.56 and 1.5
+		},
+		{
+			name: "planes start at the first vertex",
+			v:    [3]vertex{{x: 10, y: 10, r: 200}, {x: 18, y: 10, r: 200}, {x: 10, y: 18, r: 40}},
+			x:    12, y: 14,
+			want: [5]int32{120, 0, 0, 0, 0},
+		},
+	} {
+		area := edge(c.v[0], c.v[1], c.v[2].x, c.v[2].y)
+		g := newGradient(c.v, area)
+		if got := g.at(c.x, c.y); got != c.want {
+			t.Errorf("%s: at(%d, %d) = %v, want %v", c.name, c.x, c.y, got, c.want)
+		}
+	}
+}
+
+func TestDither(t *testing.T) {
+	grey := Colour{R: 0x40, G: 0x40, B: 0x40} //Dither offsets below 0 drop it to 7, the rest keep 8
+	square := []Position{{0, 0}, {4, 0}, {0, 4}, {4, 4}}
+	colours := []Colour{grey, grey, grey, grey}
+
+	for _, c := range []struct {
+		name   string
+		dither bool
+		attr   Attributes
+	}{
+		{"shaded", true, Attributes{Shaded: true}},
+		{"dithering off", false, Attributes{Shaded: true}},
+		{"flat", true, Attributes{}},
+	} {
+		r := newTestRenderer()
+		r.SetDithering(c.dither)
+		r.PushQuad(square, colours, nil, c.attr)
+
+		for y := int32(0); y < 4; y++ {
+			for x := int32(0); x < 4; x++ {
+				level := uint16(8)
+				if c.dither && c.attr.Shaded && DITHER_MATRIX[y][x] < 0 {
+					level = 7
+				}
+				want := level | level<<5 | level<<10
+				if got := r.vram.Get(x, y); got != want {
+					t.Errorf("%s (%d, %d) = 0x%04x, want 0x%04x", c.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestDitherClamps(t *testing.T) {
+	r := newTestRenderer()
+	r.SetDithering(true)
+	colours := []Colour{{R: 0xff}, {R: 0xff}, {R: 0xff}, {R: 0xff}}
+	r.PushQuad([]Position{{0, 0}, {4, 0}, {0, 4}, {4, 4}}, colours, nil, Attributes{Shaded: true})
+
+	if got := r.vram.Get(2, 1); got != 0x1f { //+3 saturates
+		t.Errorf("(2, 1) = 0x%04x, want 0x001f", got)
+	}
+	if got := r.vram.Get(0, 0); got != 0x1f { //251 still truncates to 31
+		t.Errorf("(0, 0) = 0x%04x, want 0x001f", got)
+	}
+}
+
+func TestDrawingArea(t *testing.T) {
+	const left, top, right, bottom = 2, 3, 5, 6
+	white := Colour{R: 0xff, G: 0xff, B: 0xff}
+	whites := []Colour{white, white, white, white}
+
+	for _, c := range []struct {
+		name string
+		draw func(r *SoftwareRenderer)
+		want func(x, y int32) bool //Inside the primitive, before clipping
+	}{
+		{
+			name: "triangle",
+			draw: func(r *SoftwareRenderer) {
+				r.PushTriangle([]Position{{0, 0}, {16, 0}, {0, 16}}, whites, nil, Attributes{})
+			},
+			want: func(x, y int32) bool { return x+y < 16 },
+		},
+		{
+			name: "quad",
+			draw: func(r *SoftwareRenderer) {
+				r.PushQuad([]Position{{0, 0}, {10, 0}, {0, 10}, {10, 10}}, whites, nil, Attributes{})
+			},
+			want: func(x, y int32) bool { return x < 10 && y < 10 },
+		},
+		{
+			name: "rectangle",
+			draw: func(r *SoftwareRenderer) {
+				r.PushRectangle(Position{1, 1}, 8, 8, white, TexCoord{}, Attributes{})
+			},
+			want: func(x, y int32) bool { return x >= 1 && x < 9 && y >= 1 && y < 9 },
+		},
+		{
+			name: "line",
+			draw: func(r *SoftwareRenderer) {
+				r.PushLine([]Position{{0, 4}, {9, 4}}, whites, Attributes{})
+			},
+			want: func(x, y int32) bool { return y == 4 && x <= 9 },
+		},
+	} {
+		r := newTestRenderer()
+		r.SetDrawingArea(left, top, right, bottom)
+		c.draw(r)
+
+		for y := int32(0); y < 12; y++ {
+			for x := int32(0); x < 12; x++ {
+				want := c.want(x, y) && x >= left && x <= right && y >= top && y <= bottom
+				if got := r.vram.Get(x, y) != 0; got != want {
+					t.Errorf("%s (%d, %d): drawn = %v, want %v", c.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
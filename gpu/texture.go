@@ -0,0 +1,39 @@
+package gpu
+
+// Texels equal to this aren't drawn
+const TRANSPARENT_TEXEL uint16 = 0x0000
+
+// Texel at uv, through the texture window, page and palette. The GL fragment shader
+// (glrender/ps1.fs) ports this, keep the two in step.
+func (v *VRAM) Texel(uv TexCoord, attr *Attributes) uint16 {
+	u := int32(TextureWindow(uv.U, attr.WindowMaskX, attr.WindowOffsetX))
+	w := int32(TextureWindow(uv.V, attr.WindowMaskY, attr.WindowOffsetY))
+	x, y := int32(attr.PageX), int32(attr.PageY)+w
+
+	switch attr.Depth {
+	case T4Bit: //Four palette indices per halfword
+		index := (v.Get(x+u/4, y) >> ((u & 3) * 4)) & 0xF
+		return v.Get(int32(attr.ClutX)+int32(index), int32(attr.ClutY))
+	case T8Bit: //Two per halfword
+		index := (v.Get(x+u/2, y) >> ((u & 1) * 8)) & 0xFF
+		return v.Get(int32(attr.ClutX)+int32(index), int32(attr.ClutY))
+	default:
+		return v.Get(x+u, y)
+	}
+}
+
+// The window repeats a (mask*8) sized area of the page at (offset*8)
+func TextureWindow(coord uint8, mask uint8, offset uint8) uint8 {
+	return (coord &^ (mask * 8)) | ((offset & mask) * 8)
+}
+
+// Texel colour as 8 bit channels, blended textures scale it by the vertex colour where 0x80 is 1.0
+func Modulate(texel uint16, r int32, g int32, b int32, raw bool) (int32, int32, int32) {
+	tr := int32(texel&0x1F) << 3
+	tg := int32((texel>>5)&0x1F) << 3
+	tb := int32((texel>>10)&0x1F) << 3
+	if raw {
+		return tr, tg, tb
+	}
+	return clamp8(tr * r >> 7), clamp8(tg * g >> 7), clamp8(tb * b >> 7)
+}
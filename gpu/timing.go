@@ -0,0 +1,152 @@
+package gpu
+
+// Video timings, in GPU clock cycles (11/7 of the CPU clock)
+const (
+	NTSC_CYCLES_PER_LINE uint32 = 3413
+	NTSC_LINES           uint32 = 263
+	PAL_CYCLES_PER_LINE  uint32 = 3406
+	PAL_LINES            uint32 = 314
+)
+
+// Clocks produced by the GPU during a Tick, fed to the timers
+type VideoClocks struct {
+	Dots     uint32 //Dot clock ticks
+	HBlanks  uint32 //HBlanks started
+	VBlanks  uint32 //VBlanks started
+	InHBlank bool
+	InVBlank bool
+}
+
+type Timing struct {
+	videoFrac uint32 //CPU cycles * 11 not converted yet
+	dotFrac   uint32 //GPU cycles not converted to dots yet
+	lineCycle uint32 //Position in the current line
+	line      uint32 //Current line
+	frames    uint64 //VBlanks since power on
+}
+
+// Dot clock divider for the horizontal resolution
+func (hr HorizontalRes) DotClockDivider() uint32 {
+	if hr.Val&1 != 0 { //368
+		return 7
+	}
+
+	switch hr.Val >> 1 {
+	case 0: //256
+		return 10
+	case 1: //320
+		return 8
+	case 2: //512
+		return 5
+	default: //640
+		return 4
+	}
+}
+
+func (g *GPU) LineTiming() (uint32, uint32) { //Cycles per line, lines per frame
+	if g.VMode == PAL {
+		return PAL_CYCLES_PER_LINE, PAL_LINES
+	}
+	return NTSC_CYCLES_PER_LINE, NTSC_LINES
+}
+
+func (g *GPU) Tick(cycles uint32) VideoClocks { //Advance by CPU cycles
+	var clocks VideoClocks
+	t := &g.Timing
+
+	t.videoFrac += cycles * 11
+	video := t.videoFrac / 7
+	t.videoFrac %= 7
+
+	t.dotFrac += video
+	divider := g.HRes.DotClockDivider()
+	clocks.Dots = t.dotFrac / divider
+	t.dotFrac %= divider
+
+	cyclesPerLine, lines := g.LineTiming()
+
+	for video > 0 {
+		step := cyclesPerLine - t.lineCycle
+		if step > video {
+			step = video
+		}
+		t.lineCycle += step
+		video -= step
+
+		if t.lineCycle >= cyclesPerLine {
+			t.lineCycle = 0
+			t.line = (t.line + 1) % lines
+			clocks.HBlanks++
+
+			if t.line == uint32(g.DisplayLineEnd) {
+				clocks.VBlanks++
+				g.VBlank()
+			}
+		}
+	}
+
+	clocks.InHBlank = t.lineCycle < uint32(g.DisplayHorizStart) || t.lineCycle >= uint32(g.DisplayHorizEnd)
+	clocks.InVBlank = g.InVBlank()
+
+	return clocks
+}
+
+func (g *GPU) VBlank() { //The frame is done, show it and start the next field
+	t := &g.Timing
+	t.frames++
+
+	if g.Interlaced {
+		if g.Field == Top {
+			g.Field = Bottom
+		} else {
+			g.Field = Top
+		}
+	} else {
+		g.Field = Top
+	}
+
+	g.Renderer.Display(g.DisplayArea())
+}
+
+func (g *GPU) InVBlank() bool {
+	line := g.Timing.line
+	return line < uint32(g.DisplayLineStart) || line >= uint32(g.DisplayLineEnd)
+}
+
+// Status bit 31: the field being drawn in 480 line interlaced mode, else the
+// scanline alternating. Always 0 during VBlank.
+func (g *GPU) OddLine() bool {
+	if g.InVBlank() {
+		return false
+	}
+	if g.VRes == Y480Lines && g.Interlaced {
+		return g.Field == Bottom
+	}
+	return g.Timing.line&1 != 0
+}
+
+// Part of VRAM the video output shows, from the display ranges and mode
+func (g *GPU) DisplayArea() DisplayArea {
+	area := DisplayArea{
+		X:        g.DisplayVRAMXStart,
+		Y:        g.DisplayVRAMYStart,
+		Depth24:  g.DisplayDepth == D24Bit,
+		Disabled: g.DisplayDisabled,
+	}
+
+	if g.DisplayHorizEnd > g.DisplayHorizStart { //In video cycles, counted in dots and rounded to 4
+		cycles := uint32(g.DisplayHorizEnd - g.DisplayHorizStart)
+		area.Width = uint16((cycles/g.HRes.DotClockDivider() + 2) &^ 3)
+	}
+	if g.DisplayLineEnd > g.DisplayLineStart {
+		area.Height = g.DisplayLineEnd - g.DisplayLineStart
+		if g.VRes == Y480Lines && g.Interlaced {
+			area.Height *= 2
+		}
+	}
+	return area
+}
+
+func (g *GPU) Frames() uint64 {
+	return g.Timing.frames
+}
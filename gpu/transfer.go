@@ -0,0 +1,128 @@
+package gpu
+
+// Rectangle of VRAM being streamed in or out a halfword at a time
+type VRAMTransfer struct {
+	X, Y          uint16 //Top left
+	Width, Height uint16
+	Col, Row      uint16 //Next pixel, relative to X/Y
+	Active        bool
+}
+
+func NewVRAMTransfer(position uint32, size uint32) VRAMTransfer {
+	return VRAMTransfer{
+		X:      uint16(position & 0x3FF),
+		Y:      uint16((position >> 16) & 0x1FF),
+		Width:  uint16(((size&0xFFFF)-1)&0x3FF) + 1, //0 means 1024
+		Height: uint16(((size>>16)-1)&0x1FF) + 1,    //0 means 512
+		Active: true,
+	}
+}
+
+func (t *VRAMTransfer) Words() uint32 { //Two pixels per word, rounded up
+	return (uint32(t.Width)*uint32(t.Height) + 1) / 2
+}
+
+func (t *VRAMTransfer) next() (int32, int32) { //VRAM position of the next pixel, wrapping at the edges
+	x, y := int32(t.X)+int32(t.Col), int32(t.Y)+int32(t.Row)
+	t.Col++
+	if t.Col == t.Width {
+		t.Col = 0
+		t.Row++
+		if t.Row == t.Height {
+			t.Active = false
+		}
+	}
+	return x, y
+}
+
+// Writes honour the mask bit like drawing does
+func (g *GPU) writeVRAM(x int32, y int32, pixel uint16) {
+	vram := g.Renderer.VRAM()
+	if g.PreserveMaskedPixels && vram.Get(x, y)&MASK_BIT != 0 {
+		return
+	}
+	if g.ForceSetMaskBit {
+		pixel |= MASK_BIT
+	}
+	vram.Set(x, y, pixel)
+}
+
+func (g *GPU) Gp0ImgLoad(val uint32) { //0xA0-0xBF, pixel data follows
+	if g.PreserveMaskedPixels { //Needs the mask bits drawing left
+		g.Renderer.SyncVRAM()
+	}
+	g.Upload = NewVRAMTransfer(g.Gp0Command.Index(1), g.Gp0Command.Index(2))
+	g.Gp0WordsRemaining = g.Upload.Words()
+	g.Gp0Mode = ImageLoad
+}
+
+func (g *GPU) Gp0ImgLoadWord(val uint32) {
+	for _, pixel := range [2]uint16{uint16(val), uint16(val >> 16)} {
+		if !g.Upload.Active { //Padding of odd sized uploads
+			return
+		}
+		x, y := g.Upload.next()
+		g.writeVRAM(x, y, pixel)
+		if !g.Upload.Active {
+			g.uploadWritten()
+		}
+	}
+}
+
+func (g *GPU) uploadWritten() { //Also when GP1 cuts the upload short
+	g.Renderer.VRAMWritten(g.Upload.X, g.Upload.Y, g.Upload.Width, g.Upload.Height)
+}
+
+func (g *GPU) Gp0ImgStore(val uint32) { //0xC0-0xDF, read back through GPUREAD
+	g.Renderer.SyncVRAM()
+	g.Download = NewVRAMTransfer(g.Gp0Command.Index(1), g.Gp0Command.Index(2))
+}
+
+func (g *GPU) Gp0VRAMCopy(val uint32) { //0x80-0x9F
+	src := NewVRAMTransfer(g.Gp0Command.Index(1), g.Gp0Command.Index(3))
+	dst := NewVRAMTransfer(g.Gp0Command.Index(2), g.Gp0Command.Index(3))
+	g.Renderer.SyncVRAM()
+	vram := g.Renderer.VRAM()
+
+	for src.Active {
+		sx, sy := src.next()
+		dx, dy := dst.next()
+		g.writeVRAM(dx, dy, vram.Get(sx, sy))
+	}
+	g.Renderer.VRAMWritten(dst.X, dst.Y, dst.Width, dst.Height)
+}
+
+func (g *GPU) Gp0FillRect(val uint32) { //0x02, ignores the mask bit and drawing area
+	c := CFromGP0(val)
+	pixel := uint16(c.R>>3) | uint16(c.G>>3)<<5 | uint16(c.B>>3)<<10
+
+	position := g.Gp0Command.Index(1)
+	size := g.Gp0Command.Index(2)
+	x0 := int32(position & 0x3F0) //16 pixel steps
+	y0 := int32((position >> 16) & 0x1FF)
+	w := int32(((size & 0x3FF) + 0xF) &^ 0xF)
+	h := int32((size >> 16) & 0x1FF)
+
+	vram := g.Renderer.VRAM()
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			vram.Set(x, y, pixel)
+		}
+	}
+	g.Renderer.VRAMWritten(uint16(x0), uint16(y0), uint16(w), uint16(h))
+}
+
+func (g *GPU) Read() uint32 { //GPUREAD, VRAM during a 0xC0 download, else the last value latched
+	if !g.Download.Active {
+		return g.GpuRead
+	}
+
+	vram := g.Renderer.VRAM()
+	var word uint32
+	for i := 0; i < 2 && g.Download.Active; i++ {
+		x, y := g.Download.next()
+		word |= uint32(vram.Get(x, y)) << (16 * i)
+	}
+	g.GpuRead = word
+	return word
+}
@@ -0,0 +1,74 @@
+package gpu
+
+import (
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+const (
+	VRAM_WIDTH  = 1024
+	VRAM_HEIGHT = 512
+)
+
+// 1MB of video RAM as 1024x512 16bpp pixels, mask bit in bit 15 and BGR555 below it
+type VRAM struct {
+	Pixels [VRAM_WIDTH * VRAM_HEIGHT]uint16
+}
+
+func NewVRAM() *VRAM {
+	return &VRAM{}
+}
+
+func (v *VRAM) Get(x int32, y int32) uint16 { //Coordinates wrap around
+	return v.Pixels[(y&(VRAM_HEIGHT-1))*VRAM_WIDTH+(x&(VRAM_WIDTH-1))]
+}
+
+func (v *VRAM) Set(x int32, y int32, pixel uint16) {
+	v.Pixels[(y&(VRAM_HEIGHT-1))*VRAM_WIDTH+(x&(VRAM_WIDTH-1))] = pixel
+}
+
+func (v *VRAM) Dump(w io.Writer) error { //Raw little endian pixels
+	return binary.Write(w, binary.LittleEndian, v.Pixels[:])
+}
+
+func (v *VRAM) Image() *image.RGBA { //15 bit colours widened to 8 bits, mask bit dropped
+	img := image.NewRGBA(image.Rect(0, 0, VRAM_WIDTH, VRAM_HEIGHT))
+	for i, p := range v.Pixels {
+		setRGB555(img.Pix[i*4:], p)
+	}
+	return img
+}
+
+func (v *VRAM) DisplayImage(area DisplayArea) *image.RGBA { //What the TV shows, black when disabled
+	img := image.NewRGBA(image.Rect(0, 0, int(area.Width), int(area.Height)))
+	for y := 0; y < int(area.Height); y++ {
+		vy := int32(area.Y) + int32(y)
+		for x := 0; x < int(area.Width); x++ {
+			pix := img.Pix[(y*int(area.Width)+x)*4:]
+			pix[3] = 0xff
+			if area.Disabled {
+				continue
+			}
+
+			if !area.Depth24 {
+				setRGB555(pix, v.Get(int32(area.X)+int32(x), vy))
+				continue
+			}
+
+			byteX := int32(area.X)*2 + int32(x)*3 //Three bytes per pixel across the halfwords
+			for c := int32(0); c < 3; c++ {
+				b := byteX + c
+				pix[c] = uint8(v.Get(b/2, vy) >> (8 * (b & 1)))
+			}
+		}
+	}
+	return img
+}
+
+func setRGB555(pix []uint8, p uint16) {
+	pix[0] = uint8(p<<3) | uint8(p>>2)&7
+	pix[1] = uint8(p>>5<<3) | uint8(p>>7)&7
+	pix[2] = uint8(p>>10<<3) | uint8(p>>12)&7
+	pix[3] = 0xff
+}
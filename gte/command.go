@@ -0,0 +1,379 @@
+package gte
+
+import (
+	"log"
+)
+
+// Decoded fields of a COP2 command word
+type Command uint32
+
+func (c Command) Opcode() uint32 {
+	return uint32(c) & 0x3f
+}
+
+func (c Command) Shift() uint32 { //sf: 12 or 0
+	return ((uint32(c) >> 19) & 1) * 12
+}
+
+func (c Command) Lm() bool { //Saturate IR to 0..7fff instead of -8000..7fff
+	return (uint32(c)>>10)&1 != 0
+}
+
+func (c Command) MatrixSel() uint32 { //MVMVA only
+	return (uint32(c) >> 17) & 3
+}
+
+func (c Command) VectorSel() uint32 { //MVMVA only
+	return (uint32(c) >> 15) & 3
+}
+
+func (c Command) TranslationSel() uint32 { //MVMVA only
+	return (uint32(c) >> 13) & 3
+}
+
+func (g *GTE) Command(cmd uint32) {
+	c := Command(cmd)
+	g.flags = 0
+
+	switch c.Opcode() {
+	case 0x01:
+		g.rtps(c, 0, true)
+	case 0x06:
+		g.nclip()
+	case 0x0c:
+		g.op(c)
+	case 0x10:
+		g.dpcs(c, g.rgbc)
+	case 0x11:
+		g.intpl(c)
+	case 0x12:
+		g.mvmva(c)
+	case 0x13:
+		g.ncds(c, 0)
+	case 0x14:
+		g.cdp(c)
+	case 0x16:
+		g.ncds(c, 0)
+		g.ncds(c, 1)
+		g.ncds(c, 2)
+	case 0x1b:
+		g.nccs(c, 0)
+	case 0x1c:
+		g.cc(c)
+	case 0x1e:
+		g.ncs(c, 0)
+	case 0x20:
+		g.ncs(c, 0)
+		g.ncs(c, 1)
+		g.ncs(c, 2)
+	case 0x28:
+		g.sqr(c)
+	case 0x29:
+		g.dcpl(c)
+	case 0x2a:
+		g.dpcs(c, g.rgb[0])
+		g.dpcs(c, g.rgb[0])
+		g.dpcs(c, g.rgb[0])
+	case 0x2d:
+		g.avsz3()
+	case 0x2e:
+		g.avsz4()
+	case 0x30:
+		g.rtps(c, 0, false)
+		g.rtps(c, 1, false)
+		g.rtps(c, 2, true)
+	case 0x3d:
+		g.gpf(c)
+	case 0x3e:
+		g.gpl(c)
+	case 0x3f:
+		g.nccs(c, 0)
+		g.nccs(c, 1)
+		g.nccs(c, 2)
+	default:
+		log.Printf("Unhandled GTE command: 0x%02x", c.Opcode())
+	}
+}
+
+func (g *GTE) rtps(c Command, index int, last bool) { //Perspective transformation
+	v := g.v[index]
+	var mac [3]int64
+
+	for i := 0; i < 3; i++ {
+		acc := g.checkMac(i+1, int64(g.tr[i])<<12)
+		acc = g.checkMac(i+1, acc+int64(g.rt[i][0])*int64(v[0]))
+		acc = g.checkMac(i+1, acc+int64(g.rt[i][1])*int64(v[1]))
+		acc = g.checkMac(i+1, acc+int64(g.rt[i][2])*int64(v[2]))
+		mac[i] = acc
+		g.mac[i+1] = int32(acc >> c.Shift())
+	}
+
+	g.ir[1] = g.saturateIR(1, g.mac[1], c.Lm())
+	g.ir[2] = g.saturateIR(2, g.mac[2], c.Lm())
+
+	// IR3's flag is checked against MAC3 >> 12 whatever sf says
+	z := mac[2] >> 12
+	if z < -0x8000 || z > 0x7fff {
+		g.flags |= FlagIR3Sat
+	}
+	g.ir[3] = clampIR(g.mac[3], c.Lm())
+
+	g.pushSZ(z)
+
+	n := g.divide()
+
+	x := g.checkMac0(int64(n)*int64(g.ir[1]) + int64(g.ofx))
+	y := g.checkMac0(int64(n)*int64(g.ir[2]) + int64(g.ofy))
+	g.pushSXY(g.saturateSXY(x>>16, FlagSX2Sat), g.saturateSXY(y>>16, FlagSY2Sat))
+
+	if last {
+		dq := g.checkMac0(int64(n)*int64(g.dqa) + int64(g.dqb))
+		g.mac[0] = int32(dq)
+		g.ir[0] = g.saturateIR0(dq >> 12)
+	} else {
+		g.mac[0] = int32(y)
+	}
+}
+
+func (g *GTE) nclip() { //Normal clipping
+	x0, y0 := int64(g.sxy[0][0]), int64(g.sxy[0][1])
+	x1, y1 := int64(g.sxy[1][0]), int64(g.sxy[1][1])
+	x2, y2 := int64(g.sxy[2][0]), int64(g.sxy[2][1])
+
+	v := x0*y1 + x1*y2 + x2*y0 - x0*y2 - x1*y0 - x2*y1
+	g.mac[0] = int32(g.checkMac0(v))
+}
+
+func (g *GTE) op(c Command) { //Outer product of 2 vectors
+	d1, d2, d3 := int64(g.rt[0][0]), int64(g.rt[1][1]), int64(g.rt[2][2])
+	ir1, ir2, ir3 := int64(g.ir[1]), int64(g.ir[2]), int64(g.ir[3])
+
+	g.setMacIR([3]int64{
+		g.checkMac(1, ir3*d2-ir2*d3),
+		g.checkMac(2, ir1*d3-ir3*d1),
+		g.checkMac(3, ir2*d1-ir1*d2),
+	}, c)
+}
+
+func (g *GTE) sqr(c Command) { //Square of vector IR
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		ir := int64(g.ir[i+1])
+		mac[i] = g.checkMac(i+1, ir*ir)
+	}
+	g.setMacIR(mac, c)
+}
+
+func (g *GTE) avsz3() { //Average of 3 Z values
+	sum := int64(g.sz[1]) + int64(g.sz[2]) + int64(g.sz[3])
+	v := g.checkMac0(int64(g.zsf3) * sum)
+	g.mac[0] = int32(v)
+	g.otz = g.saturateZ(v >> 12)
+}
+
+func (g *GTE) avsz4() { //Average of 4 Z values
+	sum := int64(g.sz[0]) + int64(g.sz[1]) + int64(g.sz[2]) + int64(g.sz[3])
+	v := g.checkMac0(int64(g.zsf4) * sum)
+	g.mac[0] = int32(v)
+	g.otz = g.saturateZ(v >> 12)
+}
+
+func (g *GTE) mvmva(c Command) { //Multiply vector by matrix and add vector
+	var m Matrix
+	switch c.MatrixSel() {
+	case 0:
+		m = g.rt
+	case 1:
+		m = g.llm
+	case 2:
+		m = g.lcm
+	default: //Garbage matrix
+		r := int16(uint16(g.rgbc[0]) << 4)
+		m = Matrix{
+			{-r, r, g.ir[0]},
+			{g.rt[0][2], g.rt[0][2], g.rt[0][2]},
+			{g.rt[1][1], g.rt[1][1], g.rt[1][1]},
+		}
+	}
+
+	var v [3]int16
+	switch c.VectorSel() {
+	case 0, 1, 2:
+		v = g.v[c.VectorSel()]
+	default:
+		v = [3]int16{g.ir[1], g.ir[2], g.ir[3]}
+	}
+
+	var t [3]int32
+	switch c.TranslationSel() {
+	case 0:
+		t = g.tr
+	case 1:
+		t = g.bk
+	case 2:
+		t = g.fc
+	default:
+		t = [3]int32{}
+	}
+
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		if c.TranslationSel() == 2 {
+			// Hardware bug: the first column only contributes to the flags
+			acc := g.checkMac(i+1, int64(t[i])<<12)
+			acc = g.checkMac(i+1, acc+int64(m[i][0])*int64(v[0]))
+			g.saturateIR(i+1, int32(acc>>c.Shift()), false)
+
+			acc = g.checkMac(i+1, int64(m[i][1])*int64(v[1]))
+			mac[i] = g.checkMac(i+1, acc+int64(m[i][2])*int64(v[2]))
+			continue
+		}
+
+		acc := g.checkMac(i+1, int64(t[i])<<12)
+		acc = g.checkMac(i+1, acc+int64(m[i][0])*int64(v[0]))
+		acc = g.checkMac(i+1, acc+int64(m[i][1])*int64(v[1]))
+		mac[i] = g.checkMac(i+1, acc+int64(m[i][2])*int64(v[2]))
+	}
+
+	g.setMacIR(mac, c)
+}
+
+func (g *GTE) ncs(c Command, index int) { //Normal colour single
+	g.light(c, index)
+	g.pushColour()
+}
+
+func (g *GTE) nccs(c Command, index int) { //Normal colour colour single
+	g.light(c, index)
+	g.setMacIR(g.colourIR(), c)
+	g.pushColour()
+}
+
+func (g *GTE) ncds(c Command, index int) { //Normal colour depth cue single
+	g.light(c, index)
+	g.depthCue(c, g.colourIR())
+	g.pushColour()
+}
+
+func (g *GTE) cc(c Command) { //Colour colour
+	g.lightColour(c)
+	g.setMacIR(g.colourIR(), c)
+	g.pushColour()
+}
+
+func (g *GTE) cdp(c Command) { //Colour depth cue
+	g.lightColour(c)
+	g.depthCue(c, g.colourIR())
+	g.pushColour()
+}
+
+func (g *GTE) dcpl(c Command) { //Depth cue colour light
+	g.depthCue(c, g.colourIR())
+	g.pushColour()
+}
+
+func (g *GTE) dpcs(c Command, rgb [4]uint8) { //Depth cue single, DPCT uses the FIFO
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		mac[i] = g.checkMac(i+1, int64(rgb[i])<<16)
+	}
+	g.depthCue(c, mac)
+	g.pushColour()
+}
+
+func (g *GTE) intpl(c Command) { //Interpolation of IR and far colour
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		mac[i] = g.checkMac(i+1, int64(g.ir[i+1])<<12)
+	}
+	g.depthCue(c, mac)
+	g.pushColour()
+}
+
+func (g *GTE) gpf(c Command) { //General purpose interpolation
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		mac[i] = g.checkMac(i+1, int64(g.ir[0])*int64(g.ir[i+1]))
+	}
+	g.setMacIR(mac, c)
+	g.pushColour()
+}
+
+func (g *GTE) gpl(c Command) { //General purpose interpolation with base
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		base := g.checkMac(i+1, int64(g.mac[i+1])<<c.Shift())
+		mac[i] = g.checkMac(i+1, base+int64(g.ir[0])*int64(g.ir[i+1]))
+	}
+	g.setMacIR(mac, c)
+	g.pushColour()
+}
+
+// IR = MAC = LLM * Vn, then IR = MAC = BK + LCM * IR
+func (g *GTE) light(c Command, index int) {
+	v := g.v[index]
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		acc := g.checkMac(i+1, int64(g.llm[i][0])*int64(v[0]))
+		acc = g.checkMac(i+1, acc+int64(g.llm[i][1])*int64(v[1]))
+		mac[i] = g.checkMac(i+1, acc+int64(g.llm[i][2])*int64(v[2]))
+	}
+	g.setMacIR(mac, c)
+	g.lightColour(c)
+}
+
+func (g *GTE) lightColour(c Command) {
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		acc := g.checkMac(i+1, int64(g.bk[i])<<12)
+		acc = g.checkMac(i+1, acc+int64(g.lcm[i][0])*int64(g.ir[1]))
+		acc = g.checkMac(i+1, acc+int64(g.lcm[i][1])*int64(g.ir[2]))
+		mac[i] = g.checkMac(i+1, acc+int64(g.lcm[i][2])*int64(g.ir[3]))
+	}
+	g.setMacIR(mac, c)
+}
+
+func (g *GTE) colourIR() [3]int64 { //[R*IR1, G*IR2, B*IR3] << 4
+	var mac [3]int64
+	for i := 0; i < 3; i++ {
+		mac[i] = g.checkMac(i+1, (int64(g.rgbc[i])*int64(g.ir[i+1]))<<4)
+	}
+	return mac
+}
+
+// MAC = MAC + (FC - MAC) * IR0, then shifted into MAC/IR
+func (g *GTE) depthCue(c Command, mac [3]int64) {
+	for i := 0; i < 3; i++ {
+		fc := g.checkMac(i+1, int64(g.fc[i])<<12)
+		diff := g.checkMac(i+1, fc-mac[i])
+		ir := g.saturateIR(i+1, int32(diff>>c.Shift()), false)
+		mac[i] = g.checkMac(i+1, int64(ir)*int64(g.ir[0])+mac[i])
+	}
+	g.setMacIR(mac, c)
+}
+
+func (g *GTE) setMacIR(mac [3]int64, c Command) {
+	for i := 0; i < 3; i++ {
+		g.mac[i+1] = int32(mac[i] >> c.Shift())
+		g.ir[i+1] = g.saturateIR(i+1, g.mac[i+1], c.Lm())
+	}
+}
+
+func (g *GTE) pushColour() { //Colour FIFO gets MAC / 16
+	col := [4]uint8{
+		g.saturateColour(g.mac[1]>>4, FlagRSat),
+		g.saturateColour(g.mac[2]>>4, FlagGSat),
+		g.saturateColour(g.mac[3]>>4, FlagBSat),
+		g.rgbc[3],
+	}
+	g.rgb[0] = g.rgb[1]
+	g.rgb[1] = g.rgb[2]
+	g.rgb[2] = col
+}
+
+func (g *GTE) pushSZ(z int64) {
+	g.sz[0] = g.sz[1]
+	g.sz[1] = g.sz[2]
+	g.sz[2] = g.sz[3]
+	g.sz[3] = g.saturateZ(z)
+}
@@ -0,0 +1,271 @@
+package gte
+
+// Geometry Transformation Engine, the R3000A's coprocessor 2
+type GTE struct {
+	// Data registers
+	v    [3][3]int16 //V0, V1, V2 (x, y, z)
+	rgbc [4]uint8    //Colour and GPU code
+	otz  uint16      //Average Z
+	ir   [4]int16    //IR0-IR3
+	sxy  [3][2]int16 //Screen XY FIFO
+	sz   [4]uint16   //Screen Z FIFO
+	rgb  [3][4]uint8 //Colour FIFO
+	res1 uint32      //Reserved, but read/write
+	mac  [4]int32    //MAC0-MAC3
+	lzcs uint32      //Leading zero count source
+	lzcr uint32      //Leading zero count result
+
+	// Control registers
+	rt    Matrix   //Rotation
+	tr    [3]int32 //Translation
+	llm   Matrix   //Light source
+	bk    [3]int32 //Background colour
+	lcm   Matrix   //Light colour
+	fc    [3]int32 //Far colour
+	ofx   int32    //Screen offset X
+	ofy   int32    //Screen offset Y
+	h     uint16   //Projection plane distance
+	dqa   int16    //Depth cueing coefficient
+	dqb   int32    //Depth cueing offset
+	zsf3  int16    //Average Z scale for 3 points
+	zsf4  int16    //Average Z scale for 4 points
+	flags uint32
+}
+
+type Matrix [3][3]int16
+
+// FLAG register bits
+const (
+	FlagIR0Sat    uint32 = 1 << 12
+	FlagSY2Sat    uint32 = 1 << 13
+	FlagSX2Sat    uint32 = 1 << 14
+	FlagMAC0Neg   uint32 = 1 << 15
+	FlagMAC0Pos   uint32 = 1 << 16
+	FlagDivide    uint32 = 1 << 17
+	FlagSZ3OTZSat uint32 = 1 << 18
+	FlagBSat      uint32 = 1 << 19
+	FlagGSat      uint32 = 1 << 20
+	FlagRSat      uint32 = 1 << 21
+	FlagIR3Sat    uint32 = 1 << 22
+	FlagIR2Sat    uint32 = 1 << 23
+	FlagIR1Sat    uint32 = 1 << 24
+	FlagMAC3Neg   uint32 = 1 << 25
+	FlagMAC2Neg   uint32 = 1 << 26
+	FlagMAC1Neg   uint32 = 1 << 27
+	FlagMAC3Pos   uint32 = 1 << 28
+	FlagMAC2Pos   uint32 = 1 << 29
+	FlagMAC1Pos   uint32 = 1 << 30
+	FlagError     uint32 = 1 << 31
+
+	flagErrorMask uint32 = 0x7f87e000 //Bits 30-23 and 18-13
+)
+
+func New() *GTE {
+	return &GTE{}
+}
+
+func (g *GTE) Data(reg uint32) uint32 { //MFC2, SWC2
+	switch reg {
+	case 0, 2, 4:
+		v := g.v[reg>>1]
+		return uint32(uint16(v[0])) | uint32(uint16(v[1]))<<16
+	case 1, 3, 5:
+		return uint32(int32(g.v[reg>>1][2]))
+	case 6:
+		return uint32(g.rgbc[0]) | uint32(g.rgbc[1])<<8 | uint32(g.rgbc[2])<<16 | uint32(g.rgbc[3])<<24
+	case 7:
+		return uint32(g.otz)
+	case 8, 9, 10, 11:
+		return uint32(int32(g.ir[reg-8]))
+	case 12, 13, 14:
+		xy := g.sxy[reg-12]
+		return uint32(uint16(xy[0])) | uint32(uint16(xy[1]))<<16
+	case 15:
+		xy := g.sxy[2]
+		return uint32(uint16(xy[0])) | uint32(uint16(xy[1]))<<16
+	case 16, 17, 18, 19:
+		return uint32(g.sz[reg-16])
+	case 20, 21, 22:
+		c := g.rgb[reg-20]
+		return uint32(c[0]) | uint32(c[1])<<8 | uint32(c[2])<<16 | uint32(c[3])<<24
+	case 23:
+		return g.res1
+	case 24, 25, 26, 27:
+		return uint32(g.mac[reg-24])
+	case 28, 29:
+		return g.orgb()
+	case 30:
+		return g.lzcs
+	case 31:
+		return g.lzcr
+	default:
+		panic("Unreachable")
+	}
+}
+
+func (g *GTE) SetData(reg uint32, val uint32) { //MTC2, LWC2
+	switch reg {
+	case 0, 2, 4:
+		g.v[reg>>1][0] = int16(val)
+		g.v[reg>>1][1] = int16(val >> 16)
+	case 1, 3, 5:
+		g.v[reg>>1][2] = int16(val)
+	case 6:
+		g.rgbc = [4]uint8{uint8(val), uint8(val >> 8), uint8(val >> 16), uint8(val >> 24)}
+	case 7:
+		g.otz = uint16(val)
+	case 8, 9, 10, 11:
+		g.ir[reg-8] = int16(val)
+	case 12, 13, 14:
+		g.sxy[reg-12] = [2]int16{int16(val), int16(val >> 16)}
+	case 15:
+		g.pushSXY(int16(val), int16(val>>16))
+	case 16, 17, 18, 19:
+		g.sz[reg-16] = uint16(val)
+	case 20, 21, 22:
+		g.rgb[reg-20] = [4]uint8{uint8(val), uint8(val >> 8), uint8(val >> 16), uint8(val >> 24)}
+	case 23:
+		g.res1 = val
+	case 24, 25, 26, 27:
+		g.mac[reg-24] = int32(val)
+	case 28:
+		g.ir[1] = int16((val & 0x1f) << 7)
+		g.ir[2] = int16(((val >> 5) & 0x1f) << 7)
+		g.ir[3] = int16(((val >> 10) & 0x1f) << 7)
+	case 29, 31:
+		//Read only
+	case 30:
+		g.lzcs = val
+		g.lzcr = leadingCount(val)
+	default:
+		panic("Unreachable")
+	}
+}
+
+func (g *GTE) Control(reg uint32) uint32 { //CFC2
+	switch reg {
+	case 0, 1, 2, 3, 4:
+		return g.rt.reg(reg)
+	case 5, 6, 7:
+		return uint32(g.tr[reg-5])
+	case 8, 9, 10, 11, 12:
+		return g.llm.reg(reg - 8)
+	case 13, 14, 15:
+		return uint32(g.bk[reg-13])
+	case 16, 17, 18, 19, 20:
+		return g.lcm.reg(reg - 16)
+	case 21, 22, 23:
+		return uint32(g.fc[reg-21])
+	case 24:
+		return uint32(g.ofx)
+	case 25:
+		return uint32(g.ofy)
+	case 26:
+		return uint32(int32(int16(g.h))) //Sign extended on read, unsigned in use
+	case 27:
+		return uint32(int32(g.dqa))
+	case 28:
+		return uint32(g.dqb)
+	case 29:
+		return uint32(int32(g.zsf3))
+	case 30:
+		return uint32(int32(g.zsf4))
+	case 31:
+		return g.Flags()
+	default:
+		panic("Unreachable")
+	}
+}
+
+func (g *GTE) SetControl(reg uint32, val uint32) { //CTC2
+	switch reg {
+	case 0, 1, 2, 3, 4:
+		g.rt.setReg(reg, val)
+	case 5, 6, 7:
+		g.tr[reg-5] = int32(val)
+	case 8, 9, 10, 11, 12:
+		g.llm.setReg(reg-8, val)
+	case 13, 14, 15:
+		g.bk[reg-13] = int32(val)
+	case 16, 17, 18, 19, 20:
+		g.lcm.setReg(reg-16, val)
+	case 21, 22, 23:
+		g.fc[reg-21] = int32(val)
+	case 24:
+		g.ofx = int32(val)
+	case 25:
+		g.ofy = int32(val)
+	case 26:
+		g.h = uint16(val)
+	case 27:
+		g.dqa = int16(val)
+	case 28:
+		g.dqb = int32(val)
+	case 29:
+		g.zsf3 = int16(val)
+	case 30:
+		g.zsf4 = int16(val)
+	case 31:
+		g.flags = val & 0x7ffff000
+	default:
+		panic("Unreachable")
+	}
+}
+
+// FLAG register, with the error summary bit recomputed
+func (g *GTE) Flags() uint32 {
+	f := g.flags & 0x7ffff000
+	if f&flagErrorMask != 0 {
+		f |= FlagError
+	}
+	return f
+}
+
+func (m *Matrix) reg(index uint32) uint32 { //Two elements packed per register
+	if index == 4 {
+		return uint32(int32(m[2][2]))
+	}
+	a := m[(index*2)/3][(index*2)%3]
+	b := m[(index*2+1)/3][(index*2+1)%3]
+	return uint32(uint16(a)) | uint32(uint16(b))<<16
+}
+
+func (m *Matrix) setReg(index uint32, val uint32) {
+	if index == 4 {
+		m[2][2] = int16(val)
+		return
+	}
+	m[(index*2)/3][(index*2)%3] = int16(val)
+	m[(index*2+1)/3][(index*2+1)%3] = int16(val >> 16)
+}
+
+func (g *GTE) orgb() uint32 { //IR1-3 packed back to 5 bits each
+	r := uint32(0)
+	for i := 0; i < 3; i++ {
+		c := g.ir[i+1] >> 7
+		if c < 0 {
+			c = 0
+		} else if c > 0x1f {
+			c = 0x1f
+		}
+		r |= uint32(c) << (5 * i)
+	}
+	return r
+}
+
+func (g *GTE) pushSXY(x, y int16) {
+	g.sxy[0] = g.sxy[1]
+	g.sxy[1] = g.sxy[2]
+	g.sxy[2] = [2]int16{x, y}
+}
+
+func leadingCount(val uint32) uint32 { //Leading zeroes if positive, leading ones if negative
+	if int32(val) < 0 {
+		val = ^val
+	}
+	n := uint32(0)
+	for n < 32 && val&(1<<(31-n)) == 0 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,233 @@
+package gte
+
+import (
+	"testing"
+)
+
+// Register numbers used by the vectors
+const (
+	rV0XY, rV0Z, rV1XY, rV1Z, rV2XY, rV2Z = 0, 1, 2, 3, 4, 5
+	rRGBC, rOTZ                           = 6, 7
+	rIR0, rIR1, rIR2, rIR3                = 8, 9, 10, 11
+	rSXY0, rSXY1, rSXY2                   = 12, 13, 14
+	rSZ0, rSZ1, rSZ2, rSZ3                = 16, 17, 18, 19
+	rRGB2                                 = 22
+	rMAC0, rMAC1, rMAC2, rMAC3            = 24, 25, 26, 27
+
+	cTRX, cTRY, cTRZ         = 5, 6, 7
+	cFCR, cFCG, cFCB         = 21, 22, 23
+	cOFX, cOFY, cH           = 24, 25, 26
+	cDQA, cDQB, cZSF3, cZSF4 = 27, 28, 29, 30
+)
+
+// Full COP2 command words
+const (
+	RTPS        = 0x4a180001 //sf=1
+	RTPT        = 0x4a280030 //sf=1
+	NCLIP       = 0x4b400006
+	MVMVA_RT_TR = 0x4a480012 //sf=1, RT * V0 + TR
+	MVMVA_LM    = 0x4a480412 //sf=1, lm=1, RT * V0 + TR
+	MVMVA_FC    = 0x4a484012 //sf=1, RT * V0 + FC
+	NCDS        = 0x4ae80413 //sf=1, lm=1
+	NCCS        = 0x4b08041b //sf=1, lm=1
+	DPCS        = 0x4a780010 //sf=1
+	AVSZ3       = 0x4b58002d
+	AVSZ4       = 0x4b68002e
+)
+
+// Unit matrices at 1.0 = 0x1000
+var (
+	rtIdentity  = map[uint32]uint32{0: 0x1000, 2: 0x1000, 4: 0x1000}
+	llmIdentity = map[uint32]uint32{8: 0x1000, 10: 0x1000, 12: 0x1000}
+	lcmIdentity = map[uint32]uint32{16: 0x1000, 18: 0x1000, 20: 0x1000}
+)
+
+func merge(maps ...map[uint32]uint32) map[uint32]uint32 {
+	r := map[uint32]uint32{}
+	for _, m := range maps {
+		for k, v := range m {
+			r[k] = v
+		}
+	}
+	return r
+}
+
+var VECTORS = []struct {
+	name    string
+	control map[uint32]uint32
+	data    map[uint32]uint32
+	cmd     uint32
+	want    map[uint32]uint32 //Data registers after the command
+	flag    uint32
+}{
+	{
+		name: "RTPS",
+		control: merge(rtIdentity, map[uint32]uint32{
+			cOFX: 0x100 << 16, cOFY: 0x80 << 16, cH: 0x100, cDQA: 0x10, cDQB: 0x100000,
+		}),
+		data: map[uint32]uint32{rV0XY: 0x00200010, rV0Z: 0x100},
+		cmd:  RTPS,
+		want: map[uint32]uint32{
+			rMAC1: 0x10, rMAC2: 0x20, rMAC3: 0x100, rIR1: 0x10, rIR2: 0x20, rIR3: 0x100,
+			rSZ3: 0x100, rSXY2: 0x00a00110, rMAC0: 0x200000, rIR0: 0x200,
+		},
+	},
+	{
+		name: "RTPS divide overflow",
+		control: merge(rtIdentity, map[uint32]uint32{
+			cOFX: 0x100 << 16, cOFY: 0x80 << 16, cH: 0x200, cDQA: 0x10, cDQB: 0x100000,
+		}),
+		data: map[uint32]uint32{rV0XY: 0x00200010, rV0Z: 0x100},
+		cmd:  RTPS,
+		want: map[uint32]uint32{rSZ3: 0x100, rSXY2: 0x00bf011f, rMAC0: 0x2ffff0, rIR0: 0x2ff},
+		flag: FlagError | FlagDivide,
+	},
+	{
+		name:    "RTPS UNR divide",
+		control: merge(rtIdentity, map[uint32]uint32{cH: 1000, cDQA: 1}),
+		data:    map[uint32]uint32{rV0Z: 4660},
+		cmd:     RTPS,
+		want:    map[uint32]uint32{rSZ3: 4660, rMAC0: 0x36f0, rIR0: 3}, //65536000 / 4660 = 14063.5
+	},
+	{
+		name:    "RTPS UNR divide near the limit, IR3 flagged from MAC3",
+		control: merge(rtIdentity, map[uint32]uint32{cTRZ: 0x8001, cH: 0xffff, cDQA: 1}),
+		cmd:     RTPS,
+		want:    map[uint32]uint32{rSZ3: 0x8001, rMAC3: 0x8001, rIR3: 0x7fff, rMAC0: 0x1fffa, rIR0: 0x1f},
+		flag:    FlagIR3Sat, //Bits 19-22 stay out of the error summary
+	},
+	{
+		name:    "RTPT",
+		control: merge(rtIdentity, map[uint32]uint32{cH: 0x100, cDQA: 0x10}),
+		data: map[uint32]uint32{
+			rV0XY: 0x00200010, rV0Z: 0x100,
+			rV1XY: 0x00400040, rV1Z: 0x200,
+			rV2XY: 0x0080ff80, rV2Z: 0x400,
+		},
+		cmd: RTPT,
+		want: map[uint32]uint32{
+			rSXY0: 0x00200010, rSXY1: 0x00200020, rSXY2: 0x0020ffe0,
+			rSZ0: 0, rSZ1: 0x100, rSZ2: 0x200, rSZ3: 0x400,
+			rMAC0: 0x40000, rIR0: 0x40,
+		},
+	},
+	{
+		name: "NCLIP",
+		data: map[uint32]uint32{rSXY0: 0, rSXY1: 0x0000000a, rSXY2: 0x000a0000},
+		cmd:  NCLIP,
+		want: map[uint32]uint32{rMAC0: 100},
+	},
+	{
+		name: "NCLIP overflow",
+		data: map[uint32]uint32{rSXY0: 0x80008000, rSXY1: 0x80007fff, rSXY2: 0x7fff8000},
+		cmd:  NCLIP,
+		want: map[uint32]uint32{rMAC0: 0xfffe0001},
+		flag: FlagError | FlagMAC0Pos,
+	},
+	{
+		name:    "MVMVA RT*V0+TR",
+		control: merge(rtIdentity, map[uint32]uint32{cTRX: 1, cTRY: 2, cTRZ: 3}),
+		data:    map[uint32]uint32{rV0XY: 0x00200010, rV0Z: 0x30},
+		cmd:     MVMVA_RT_TR,
+		want:    map[uint32]uint32{rMAC1: 0x11, rMAC2: 0x22, rMAC3: 0x33, rIR1: 0x11, rIR2: 0x22, rIR3: 0x33},
+	},
+	{
+		name:    "MVMVA lm clamps IR",
+		control: rtIdentity,
+		data:    map[uint32]uint32{rV0XY: 0x0020fff0, rV0Z: 0x30},
+		cmd:     MVMVA_LM,
+		want:    map[uint32]uint32{rMAC1: 0xfffffff0, rIR1: 0, rIR2: 0x20, rIR3: 0x30},
+		flag:    FlagError | FlagIR1Sat,
+	},
+	{
+		name:    "MVMVA FC bug",
+		control: merge(rtIdentity, map[uint32]uint32{cFCR: 0x10000, cFCG: 0x10, cFCB: 0x20}),
+		data:    map[uint32]uint32{rV0XY: 0x00200010, rV0Z: 0x30},
+		cmd:     MVMVA_FC,
+		//FC and the first column only reach the flags, the result is the last two columns
+		want: map[uint32]uint32{rMAC1: 0, rMAC2: 0x20, rMAC3: 0x30, rIR1: 0, rIR2: 0x20, rIR3: 0x30},
+		flag: FlagError | FlagIR1Sat,
+	},
+	{
+		name:    "NCDS",
+		control: merge(llmIdentity, lcmIdentity, map[uint32]uint32{cFCR: 0x100, cFCG: 0x100, cFCB: 0x100}),
+		data:    map[uint32]uint32{rV0XY: 0x04000800, rV0Z: 0x200, rRGBC: 0x30204080, rIR0: 0x800},
+		cmd:     NCDS,
+		want: map[uint32]uint32{
+			rMAC1: 0x280, rMAC2: 0x100, rMAC3: 0xa0, rIR1: 0x280, rIR2: 0x100, rIR3: 0xa0, rRGB2: 0x300a1028,
+		},
+	},
+	{
+		name:    "NCCS",
+		control: merge(llmIdentity, lcmIdentity),
+		data:    map[uint32]uint32{rV0XY: 0x04000800, rV0Z: 0x200, rRGBC: 0x30204080},
+		cmd:     NCCS,
+		want: map[uint32]uint32{
+			rMAC1: 0x400, rMAC2: 0x100, rMAC3: 0x40, rIR1: 0x400, rIR2: 0x100, rIR3: 0x40, rRGB2: 0x30041040,
+		},
+	},
+	{
+		name:    "NCCS colour saturation",
+		control: merge(llmIdentity, lcmIdentity),
+		data:    map[uint32]uint32{rV0XY: 0x00004000, rRGBC: 0x300000ff},
+		cmd:     NCCS,
+		want:    map[uint32]uint32{rMAC1: 0x3fc0, rIR1: 0x3fc0, rRGB2: 0x300000ff},
+		flag:    FlagRSat,
+	},
+	{
+		name:    "DPCS",
+		control: map[uint32]uint32{cFCR: 0x100, cFCG: 0x100, cFCB: 0x100},
+		data:    map[uint32]uint32{rRGBC: 0x30204080, rIR0: 0x800},
+		cmd:     DPCS,
+		want: map[uint32]uint32{
+			rMAC1: 0x480, rMAC2: 0x280, rMAC3: 0x180, rIR1: 0x480, rIR2: 0x280, rIR3: 0x180, rRGB2: 0x30182848,
+		},
+	},
+	{
+		name:    "AVSZ3",
+		control: map[uint32]uint32{cZSF3: 0x155},
+		data:    map[uint32]uint32{rSZ1: 0x100, rSZ2: 0x200, rSZ3: 0x300},
+		cmd:     AVSZ3,
+		want:    map[uint32]uint32{rMAC0: 0x7fe00, rOTZ: 0x7f},
+	},
+	{
+		name:    "AVSZ3 negative OTZ",
+		control: map[uint32]uint32{cZSF3: 0xffffff00},
+		data:    map[uint32]uint32{rSZ1: 0x100, rSZ2: 0x200, rSZ3: 0x300},
+		cmd:     AVSZ3,
+		want:    map[uint32]uint32{rMAC0: 0xfffa0000, rOTZ: 0},
+		flag:    FlagError | FlagSZ3OTZSat,
+	},
+	{
+		name:    "AVSZ4",
+		control: map[uint32]uint32{cZSF4: 0x100},
+		data:    map[uint32]uint32{rSZ0: 0x100, rSZ1: 0x200, rSZ2: 0x300, rSZ3: 0x400},
+		cmd:     AVSZ4,
+		want:    map[uint32]uint32{rMAC0: 0xa0000, rOTZ: 0xa0},
+	},
+}
+
+func TestCommands(t *testing.T) {
+	for _, v := range VECTORS {
+		t.Run(v.name, func(t *testing.T) {
+			g := New()
+			for reg, val := range v.control {
+				g.SetControl(reg, val)
+			}
+			for reg, val := range v.data {
+				g.SetData(reg, val)
+			}
+
+			g.Command(v.cmd)
+
+			for reg, want := range v.want {
+				if got := g.Data(reg); got != want {
+					t.Errorf("data register %d = 0x%08x, want 0x%08x", reg, got, want)
+				}
+			}
+			if got := g.Control(31); got != v.flag {
+				t.Errorf("FLAG = 0x%08x, want 0x%08x", got, v.flag)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package gte
+
+// Unsigned Newton-Raphson reciprocal table used by the divider
+var unrTable = func() [0x101]uint8 {
+	var t [0x101]uint8
+	for i := range t {
+		v := (0x40000/(i+0x100)+1)/2 - 0x101
+		if v < 0 {
+			v = 0
+		}
+		t[i] = uint8(v)
+	}
+	return t
+}()
+
+// H / SZ3 with the hardware's rounding, saturated to 0x1ffff
+func (g *GTE) divide() uint32 {
+	h := uint32(g.h)
+	sz3 := uint32(g.sz[3])
+
+	if h >= sz3*2 {
+		g.flags |= FlagDivide
+		return 0x1ffff
+	}
+
+	z := uint32(0) //Leading zeroes of the 16 bit divisor
+	for z < 16 && sz3&(0x8000>>z) == 0 {
+		z++
+	}
+
+	n := uint64(h) << z
+	d := uint64(sz3) << z
+	u := uint64(unrTable[(d-0x7fc0)>>7]) + 0x101
+	d = (0x2000080 - d*u) >> 8
+	d = (0x80 + d*u) >> 8
+
+	q := (n*d + 0x8000) >> 16
+	if q > 0x1ffff {
+		q = 0x1ffff
+	}
+	return uint32(q)
+}
+
+// MAC1-3 overflow check on 44 bits, the result wraps like the hardware's
+func (g *GTE) checkMac(index int, v int64) int64 {
+	if v > 0x7ffffffffff {
+		g.flags |= FlagMAC1Pos >> (index - 1)
+	} else if v < -0x80000000000 {
+		g.flags |= FlagMAC1Neg >> (index - 1)
+	}
+	return (v << 20) >> 20
+}
+
+func (g *GTE) checkMac0(v int64) int64 { //MAC0 overflows on 32 bits
+	if v > 0x7fffffff {
+		g.flags |= FlagMAC0Pos
+	} else if v < -0x80000000 {
+		g.flags |= FlagMAC0Neg
+	}
+	return v
+}
+
+func clampIR(v int32, lm bool) int16 {
+	min := int32(-0x8000)
+	if lm {
+		min = 0
+	}
+	if v < min {
+		return int16(min)
+	} else if v > 0x7fff {
+		return 0x7fff
+	}
+	return int16(v)
+}
+
+func (g *GTE) saturateIR(index int, v int32, lm bool) int16 {
+	r := clampIR(v, lm)
+	if int32(r) != v {
+		g.flags |= FlagIR1Sat >> (index - 1)
+	}
+	return r
+}
+
+func (g *GTE) saturateIR0(v int64) int16 {
+	if v < 0 {
+		g.flags |= FlagIR0Sat
+		return 0
+	} else if v > 0x1000 {
+		g.flags |= FlagIR0Sat
+		return 0x1000
+	}
+	return int16(v)
+}
+
+func (g *GTE) saturateSXY(v int64, flag uint32) int16 {
+	if v < -0x400 {
+		g.flags |= flag
+		return -0x400
+	} else if v > 0x3ff {
+		g.flags |= flag
+		return 0x3ff
+	}
+	return int16(v)
+}
+
+func (g *GTE) saturateZ(v int64) uint16 { //SZ3 and OTZ
+	if v < 0 {
+		g.flags |= FlagSZ3OTZSat
+		return 0
+	} else if v > 0xffff {
+		g.flags |= FlagSZ3OTZSat
+		return 0xffff
+	}
+	return uint16(v)
+}
+
+func (g *GTE) saturateColour(v int32, flag uint32) uint8 {
+	if v < 0 {
+		g.flags |= flag
+		return 0
+	} else if v > 0xff {
+		g.flags |= flag
+		return 0xff
+	}
+	return uint8(v)
+}
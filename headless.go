@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/Koops0/GPSXE/gpu"
+)
+
+// When a headless run stops, zero means no limit
+type Headless_limits struct {
+	Frames  uint64
+	Cycles  uint64
+	UntilPC *uint32
+}
+
+// Runs without a window until a limit is hit, returns the exit status
+func Run_headless(cpu *CPU, limits Headless_limits) (status int) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Fprintf(os.Stderr, "Emulator panic at 0x%08x: %v\n%s", cpu.current_pc, err, debug.Stack())
+			status = EXIT_PANIC
+		}
+	}()
+
+	budget := EXIT_OK
+	if limits.UntilPC != nil {
+		budget = EXIT_TIMEOUT
+	}
+
+	video := cpu.inter.Gpu()
+	for {
+		if limits.UntilPC != nil && cpu.pc == *limits.UntilPC {
+			fmt.Printf("Reached 0x%08x after %d cycles, %d frames\n", cpu.pc, cpu.cycles, video.Frames())
+			return EXIT_OK
+		}
+		if limits.Frames != 0 && video.Frames() >= limits.Frames {
+			fmt.Printf("Stopped after %d frames, pc 0x%08x\n", video.Frames(), cpu.pc)
+			return budget
+		}
+		if limits.Cycles != 0 && cpu.cycles >= limits.Cycles {
+			fmt.Printf("Stopped after %d cycles, pc 0x%08x\n", cpu.cycles, cpu.pc)
+			return budget
+		}
+		cpu.Run_next()
+	}
+}
+
+// Writes VRAM, as a PNG if the name ends in .png or else raw 16 bit pixels
+func Dump_vram(cpu *CPU, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	vram := cpu.inter.Gpu().Renderer.VRAM()
+	if strings.HasSuffix(strings.ToLower(path), ".png") {
+		err = png.Encode(f, vram.Image())
+	} else {
+		err = vram.Dump(f)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func Dump_frame(cpu *CPU, path string) error {
+	software, ok := cpu.inter.Gpu().Renderer.(*gpu.SoftwareRenderer)
+	if !ok {
+		return fmt.Errorf("frames are only kept by the software renderer")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, software.Frame()); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func Dump_ram(cpu *CPU, path string) error {
+	return os.WriteFile(path, cpu.inter.Ram().Bytes(), 0644)
+}
@@ -0,0 +1,107 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Koops0/GPSXE/sio"
+)
+
+// Pad button names used in bindings, plus "analog" for the DualShock's analog button
+var BUTTONS = map[string]sio.Button{
+	"select": sio.Select, "l3": sio.L3, "r3": sio.R3, "start": sio.Start,
+	"up": sio.Up, "right": sio.Right, "down": sio.Down, "left": sio.Left,
+	"l2": sio.L2, "r2": sio.R2, "l1": sio.L1, "r1": sio.R1,
+	"triangle": sio.Triangle, "circle": sio.Circle, "cross": sio.Cross, "square": sio.Square,
+}
+
+const ANALOG_BUTTON = "analog"
+
+// Stick names for axis bindings, which may also name l2 or r2 for triggers
+var AXES = map[string]sio.Axis{
+	"rightx": sio.RightX, "righty": sio.RightY, "leftx": sio.LeftX, "lefty": sio.LeftY,
+}
+
+// Bindings file, one entry per controller port:
+//
+//	{"ports": [{"device": "dualshock", "controller": 0,
+//	            "keys": {"Return": "start", "X": "cross"},
+//	            "buttons": {"a": "cross", "guide": "analog"},
+//	            "axes": {"leftx": "leftx", "triggerleft": "l2"}}]}
+//
+// Keys use SDL key names, buttons and axes SDL game controller names.
+type Config struct {
+	Ports [2]Port `json:"ports"`
+}
+
+type Port struct {
+	Device     string            `json:"device"`     //"digital", "dualshock" or "none"
+	Controller int               `json:"controller"` //Ports take game controllers as they are plugged in, lowest first, -1 for none
+	Keys       map[string]string `json:"keys"`
+	Buttons    map[string]string `json:"buttons"`
+	Axes       map[string]string `json:"axes"`
+}
+
+// A DualShock on port 1 driven by the keyboard and the first game controller, port 2 empty
+func Default() Config {
+	return Config{Ports: [2]Port{
+		{
+			Device:     "dualshock",
+			Controller: 0,
+			Keys: map[string]string{
+				"Up": "up", "Down": "down", "Left": "left", "Right": "right",
+				"Return": "start", "Right Shift": "select",
+				"X": "cross", "C": "circle", "Z": "square", "S": "triangle",
+				"Q": "l1", "W": "l2", "E": "r1", "R": "r2", "F": "analog",
+			},
+			Buttons: map[string]string{
+				"a": "cross", "b": "circle", "x": "square", "y": "triangle",
+				"back": "select", "start": "start", "guide": "analog",
+				"leftshoulder": "l1", "rightshoulder": "r1", "leftstick": "l3", "rightstick": "r3",
+				"dpup": "up", "dpdown": "down", "dpleft": "left", "dpright": "right",
+			},
+			Axes: map[string]string{
+				"leftx": "leftx", "lefty": "lefty", "rightx": "rightx", "righty": "righty",
+				"triggerleft": "l2", "triggerright": "r2",
+			},
+		},
+		{Device: "none", Controller: -1},
+	}}
+}
+
+func Load(path string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+
+	var c Config
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Creates the configured pads and plugs them into SIO0, nil for empty ports
+func (c Config) Plug(sio0 *sio.SIO0) ([2]sio.Pad, error) {
+	var pads [2]sio.Pad
+	for n, port := range c.Ports {
+		switch strings.ToLower(port.Device) {
+		case "digital":
+			pads[n] = sio.NewDigitalPad()
+		case "dualshock":
+			pads[n] = sio.NewDualShock()
+		case "none", "":
+			continue
+		default:
+			return pads, fmt.Errorf("port %d: unknown device %q", n+1, port.Device)
+		}
+		sio0.Plug(n, pads[n])
+	}
+	return pads, nil
+}
@@ -0,0 +1,235 @@
+package sdlinput
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/Koops0/GPSXE/input"
+	"github.com/Koops0/GPSXE/sio"
+)
+
+const (
+	TRIGGER_THRESHOLD int16  = 0x4000 //Triggers bound to L2/R2 press past half way
+	RUMBLE_MS         uint32 = 100    //Refreshed every frame while the motors run, so a stalled emulator goes quiet
+)
+
+// What a key, button or axis drives on a port
+type binding struct {
+	port   int
+	button string //Pad button name or ANALOG_BUTTON, empty for sticks
+	axis   sio.Axis
+}
+
+// Feeds SDL keyboard and game controller events to the pads. Game controllers are given slots
+// as they are plugged in, the lowest free one first, and each port follows the slot in its config.
+type Mapper struct {
+	pads        [2]sio.Pad
+	slots       [2]int //Controller slot per port, -1 for none
+	keys        map[sdl.Keycode][]binding
+	buttons     map[sdl.GameControllerButton][]binding
+	axes        map[sdl.GameControllerAxis][]binding
+	controllers []*sdl.GameController //By slot, nil when free
+	rumble      [2][2]uint8           //Last motor levels sent per port
+}
+
+func NewMapper(config input.Config, pads [2]sio.Pad) (*Mapper, error) {
+	m := &Mapper{
+		pads:    pads,
+		keys:    map[sdl.Keycode][]binding{},
+		buttons: map[sdl.GameControllerButton][]binding{},
+		axes:    map[sdl.GameControllerAxis][]binding{},
+	}
+
+	for n, port := range config.Ports {
+		m.slots[n] = port.Controller
+		if pads[n] == nil {
+			continue
+		}
+
+		for name, target := range port.Keys {
+			key := sdl.GetKeyFromName(name)
+			if key == sdl.K_UNKNOWN {
+				return nil, fmt.Errorf("port %d: unknown key %q", n+1, name)
+			}
+			b, err := newBinding(n, target, false)
+			if err != nil {
+				return nil, err
+			}
+			m.keys[key] = append(m.keys[key], b)
+		}
+		for name, target := range port.Buttons {
+			button := sdl.GameControllerGetButtonFromString(name)
+			if button == sdl.CONTROLLER_BUTTON_INVALID {
+				return nil, fmt.Errorf("port %d: unknown controller button %q", n+1, name)
+			}
+			b, err := newBinding(n, target, false)
+			if err != nil {
+				return nil, err
+			}
+			m.buttons[button] = append(m.buttons[button], b)
+		}
+		for name, target := range port.Axes {
+			axis := sdl.GameControllerGetAxisFromString(name)
+			if axis == sdl.CONTROLLER_AXIS_INVALID {
+				return nil, fmt.Errorf("port %d: unknown controller axis %q", n+1, name)
+			}
+			b, err := newBinding(n, target, true)
+			if err != nil {
+				return nil, err
+			}
+			m.axes[axis] = append(m.axes[axis], b)
+		}
+	}
+	return m, nil
+}
+
+func newBinding(port int, target string, axis bool) (binding, error) {
+	if _, ok := input.BUTTONS[target]; ok || target == input.ANALOG_BUTTON {
+		return binding{port: port, button: target}, nil
+	}
+	if stick, ok := input.AXES[target]; ok && axis {
+		return binding{port: port, axis: stick}, nil
+	}
+	return binding{}, fmt.Errorf("port %d: can't bind to %q", port+1, target)
+}
+
+// Handles the events it knows, the rest are left to the caller
+func (m *Mapper) Handle(event sdl.Event) {
+	switch e := event.(type) {
+	case *sdl.KeyboardEvent:
+		if e.Repeat == 0 {
+			m.press(m.keys[e.Keysym.Sym], -1, e.State == sdl.PRESSED)
+		}
+	case *sdl.ControllerButtonEvent:
+		m.press(m.buttons[sdl.GameControllerButton(e.Button)], m.slot(e.Which), e.State == sdl.PRESSED)
+	case *sdl.ControllerAxisEvent:
+		m.move(m.axes[sdl.GameControllerAxis(e.Axis)], m.slot(e.Which), e.Value)
+	case *sdl.ControllerDeviceEvent:
+		if e.Type == sdl.CONTROLLERDEVICEADDED {
+			m.attach(int(e.Which))
+		} else if e.Type == sdl.CONTROLLERDEVICEREMOVED {
+			m.detach(e.Which)
+		}
+	}
+}
+
+func (m *Mapper) press(bindings []binding, slot int, pressed bool) { //slot -1 for the keyboard, which drives every port
+	for _, b := range bindings {
+		pad := m.pads[b.port]
+		if slot >= 0 && m.slots[b.port] != slot {
+			continue
+		}
+		if b.button != input.ANALOG_BUTTON {
+			pad.SetButton(input.BUTTONS[b.button], pressed)
+		} else if shock, ok := pad.(*sio.DualShock); ok && pressed {
+			shock.ToggleAnalog()
+		}
+	}
+}
+
+func (m *Mapper) move(bindings []binding, slot int, val int16) {
+	for _, b := range bindings {
+		if m.slots[b.port] != slot {
+			continue
+		}
+		if b.button != "" { //Only triggers rest at 0, sticks bound to buttons press either way
+			m.press([]binding{b}, slot, val >= TRIGGER_THRESHOLD || val <= -TRIGGER_THRESHOLD)
+		} else if shock, ok := m.pads[b.port].(*sio.DualShock); ok {
+			shock.SetAxis(b.axis, uint8(int(val)>>8+int(sio.STICK_CENTRE)))
+		}
+	}
+}
+
+func (m *Mapper) slot(id sdl.JoystickID) int { //-2 for controllers no port listens to
+	for n, c := range m.controllers {
+		if c != nil && c.Joystick().InstanceID() == id {
+			return n
+		}
+	}
+	return -2
+}
+
+func (m *Mapper) attach(index int) {
+	if !sdl.IsGameController(index) {
+		return
+	}
+	c := sdl.GameControllerOpen(index)
+	if c == nil {
+		return
+	}
+	if m.slot(c.Joystick().InstanceID()) >= 0 { //Already open, SDL also announces controllers present at startup
+		c.Close()
+		return
+	}
+
+	for n := range m.controllers {
+		if m.controllers[n] == nil {
+			m.controllers[n] = c
+			m.resetRumble(n)
+			return
+		}
+	}
+	m.controllers = append(m.controllers, c)
+	m.resetRumble(len(m.controllers) - 1)
+}
+
+func (m *Mapper) detach(id sdl.JoystickID) {
+	n := m.slot(id)
+	if n < 0 {
+		return
+	}
+	m.controllers[n].Close()
+	m.controllers[n] = nil
+
+	for port, pad := range m.pads { //Let go of whatever it was holding
+		if pad == nil || m.slots[port] != n {
+			continue
+		}
+		for _, button := range input.BUTTONS {
+			pad.SetButton(button, false)
+		}
+		if shock, ok := pad.(*sio.DualShock); ok {
+			for _, axis := range input.AXES {
+				shock.SetAxis(axis, sio.STICK_CENTRE)
+			}
+		}
+	}
+}
+
+func (m *Mapper) resetRumble(slot int) { //A new controller starts still
+	for port := range m.slots {
+		if m.slots[port] == slot {
+			m.rumble[port] = [2]uint8{}
+		}
+	}
+}
+
+// Drives controller rumble from the DualShock motors, call once per frame
+func (m *Mapper) Update() {
+	for port, pad := range m.pads {
+		shock, ok := pad.(*sio.DualShock)
+		slot := m.slots[port]
+		if !ok || slot < 0 || slot >= len(m.controllers) || m.controllers[slot] == nil {
+			continue
+		}
+
+		small, large := shock.Motors()
+		levels := [2]uint8{small, large}
+		if levels == m.rumble[port] && small == 0 && large == 0 {
+			continue
+		}
+		m.rumble[port] = levels
+		//The large motor carries the heavy low frequency weight, the small one the high buzz
+		m.controllers[slot].Rumble(uint16(large)*0x101, uint16(small)*0x101, RUMBLE_MS)
+	}
+}
+
+func (m *Mapper) Close() {
+	for _, c := range m.controllers {
+		if c != nil {
+			c.Close()
+		}
+	}
+	m.controllers = nil
+}
@@ -1,5 +1,11 @@
 package main
 
+import (
+	"fmt"
+
+	"github.com/Koops0/GPSXE/disasm"
+)
+
 type Instruction struct {
 	op uint32
 }
@@ -40,3 +46,7 @@ func (i Instruction) Shift() uint32 {
 func (i Instruction) Imm_jump() uint32 {
 	return i.op & 0x3ffffff
 }
+
+func (i Instruction) Disasm(pc uint32) string { //Assembly for error messages
+	return fmt.Sprintf("0x%08x: %08x  %s", pc, i.op, disasm.Disassemble(pc, i.op))
+}
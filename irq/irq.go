@@ -0,0 +1,75 @@
+package irq
+
+// Hardware interrupt lines, bit positions in I_STAT and I_MASK
+type Interrupt uint32
+
+const (
+	VBlank     Interrupt = 0
+	Gpu        Interrupt = 1
+	CdRom      Interrupt = 2
+	Dma        Interrupt = 3
+	Timer0     Interrupt = 4
+	Timer1     Interrupt = 5
+	Timer2     Interrupt = 6
+	PadMemCard Interrupt = 7
+	Sio        Interrupt = 8
+	Spu        Interrupt = 9
+	Lightpen   Interrupt = 10
+)
+
+// Interrupt controller at 0x1f801070
+type InterruptState struct {
+	status uint16 //I_STAT
+	mask   uint16 //I_MASK
+}
+
+func New() *InterruptState {
+	return &InterruptState{
+		status: 0,
+		mask:   0,
+	}
+}
+
+func (s *InterruptState) Active() bool { //Drives COP0 Cause.IP2
+	return s.status&s.mask != 0
+}
+
+func (s *InterruptState) Status() uint16 {
+	return s.status
+}
+
+func (s *InterruptState) Ack(val uint16) { //Writing 0 clears the bit, 1 leaves it alone
+	s.status &= val
+}
+
+func (s *InterruptState) Mask() uint16 {
+	return s.mask
+}
+
+func (s *InterruptState) SetMask(val uint16) {
+	s.mask = val & 0x7ff
+}
+
+func (s *InterruptState) Assert(which Interrupt) { //Edge triggered, stays set until acked
+	s.status |= 1 << which
+}
+
+func (s *InterruptState) Load(offset uint32) uint32 {
+	switch offset {
+	case 0:
+		return uint32(s.status)
+	case 4:
+		return uint32(s.mask)
+	default:
+		return 0
+	}
+}
+
+func (s *InterruptState) Store(offset uint32, val uint32) {
+	switch offset {
+	case 0:
+		s.Ack(uint16(val))
+	case 4:
+		s.SetMask(uint16(val))
+	}
+}
@@ -1,81 +1,266 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-
-	"github.com/veandco/go-sdl2/sdl"
-	"github.com/go-gl/gl/v4.6-core/gl"
+	"io"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
 
 	"github.com/Koops0/GPSXE/bios"
 	"github.com/Koops0/GPSXE/biosmap"
+	"github.com/Koops0/GPSXE/cdrom"
+	"github.com/Koops0/GPSXE/debugger"
+	"github.com/Koops0/GPSXE/exe"
+	"github.com/Koops0/GPSXE/gdbstub"
 	"github.com/Koops0/GPSXE/gpu"
+	"github.com/Koops0/GPSXE/input"
+	"github.com/Koops0/GPSXE/spu"
+	"github.com/Koops0/GPSXE/trace"
+	"github.com/Koops0/GPSXE/tty"
+)
+
+// Subcommands, run instead of the emulator
+var COMMANDS = map[string]func(args []string) int{
+	"disasm":    Disasm_command,
+	"tracedump": Tracedump_command,
+	"tracediff": Tracediff_command,
+	"traceconv": Traceconv_command,
+}
+
+// Exit statuses of the emulator
+const (
+	EXIT_OK      = 0 //Reached -until-pc, or ran out the frame/cycle budget without one
+	EXIT_TIMEOUT = 1 //Ran out the budget before reaching -until-pc
+	EXIT_ERROR   = 2 //Bad flags or setup
+	EXIT_PANIC   = 3 //The emulator crashed
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := COMMANDS[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+	os.Exit(Run_emulator())
+}
+
+func Run_emulator() int {
+	discPath := flag.String("disc", "", "CUE sheet or BIN image to insert")
+	exePath := flag.String("exe", "", "PS-X EXE to run once the BIOS is up")
+	ttyLog := flag.String("tty-log", "", "Write BIOS/DUART console output to this file instead of stdout")
+	debugMode := flag.Bool("debug", false, "Start in the interactive debugger, and fall back to it on panics")
+	tracePath := flag.String("trace", "", "Record every instruction to this binary trace file")
+	gdbAddr := flag.String("gdb", "", "Serve the GDB remote protocol on this address, e.g. :2345")
+	headless := flag.Bool("headless", false, "Run without SDL or GL, rendering in software, until a limit below is hit")
+	frames := flag.Uint64("frames", 0, "Headless: stop after this many frames")
+	cycles := flag.Uint64("cycles", 0, "Headless: stop after this many CPU cycles")
+	untilPC := flag.String("until-pc", "", "Headless: stop when the PC reaches this hex address, exit 1 if a limit is hit first")
+	dumpVram := flag.String("dump-vram", "", "Headless: write VRAM here on exit, as PNG for .png names or else raw")
+	dumpRam := flag.String("dump-ram", "", "Headless: write main RAM here on exit")
+	dumpFrame := flag.String("dump-frame", "", "Headless: write the last displayed frame here on exit, as PNG")
+	audioDump := flag.String("audio-dump", "", "Write the SPU output to this WAV file instead of the audio device")
+	inputPath := flag.String("input", "", "JSON file of pad devices and key/controller bindings per port")
+	flag.Parse()
+
+	if *debugMode && *gdbAddr != "" {
+		fmt.Println("-debug and -gdb can't be used together")
+		return EXIT_ERROR
+	}
+
+	var limits Headless_limits
+	if *headless {
+		if *debugMode || *gdbAddr != "" {
+			fmt.Println("-headless can't be used with -debug or -gdb")
+			return EXIT_ERROR
+		}
+		limits.Frames, limits.Cycles = *frames, *cycles
+		if *untilPC != "" {
+			pc, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(*untilPC), "0x"), 16, 32)
+			if err != nil {
+				fmt.Println("Invalid -until-pc:", *untilPC)
+				return EXIT_ERROR
+			}
+			limits.UntilPC = new(uint32)
+			*limits.UntilPC = uint32(pc)
+		}
+		if limits.Frames == 0 && limits.Cycles == 0 && limits.UntilPC == nil {
+			fmt.Println("-headless needs -frames, -cycles or -until-pc")
+			return EXIT_ERROR
+		}
+	}
+
+	bindings := input.Default()
+	if *inputPath != "" {
+		c, err := input.Load(*inputPath)
+		if err != nil {
+			fmt.Println("Error loading input bindings:", err)
+			return EXIT_ERROR
+		}
+		bindings = c
+	}
+
+	var program *exe.Exe
+	if *exePath != "" {
+		p, err := exe.Load(*exePath)
+		if err != nil {
+			fmt.Println("Error loading EXE:", err)
+			return EXIT_ERROR
+		}
+		program = p
+	}
+
 	bios, err := bios.New("SCPH1001.bin") //will switch to SCPH7501.bin later
 	if err != nil {
 		fmt.Println("Error reading file")
-		return
+		return EXIT_ERROR
 	}
 
-	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
-		fmt.Println("Error initializing SDL:", err)
-		return
+	var renderer gpu.Renderer
+	if *headless {
+		renderer = gpu.NewSoftware()
+	} else {
+		window, closeWindow, err := Open_window()
+		if err != nil {
+			fmt.Println("Error opening window:", err)
+			return EXIT_ERROR
+		}
+		defer closeWindow()
+		renderer = window
 	}
-	defer sdl.Quit()
-
-	// Create an SDL renderer for the window
-	renderer := gpu.Renderer{}.New()
 	gpu := gpu.GPU{}.New(renderer)
 	inter := biosmap.Interconnect{}.New(bios, gpu)
+
+	if *discPath != "" {
+		disc, err := cdrom.Open(*discPath)
+		if err != nil {
+			fmt.Println("Error loading disc:", err)
+			return EXIT_ERROR
+		}
+		defer disc.Close()
+		inter.CdRom().InsertDisc(disc)
+	}
+
 	cpu := &CPU{}
 	cpu.New(inter)
+	pads, err := bindings.Plug(cpu.inter.Sio0())
+	if err != nil {
+		fmt.Println("Error setting up pads:", err)
+		return EXIT_ERROR
+	}
+	if program != nil {
+		cpu.Sideload(program)
+	}
+
+	var ttyOut io.Writer = os.Stdout
+	if *ttyLog != "" {
+		f, err := os.Create(*ttyLog)
+		if err != nil {
+			fmt.Println("Error creating TTY log:", err)
+			return EXIT_ERROR
+		}
+		defer f.Close()
+		ttyOut = f
+	}
+	console := tty.New(ttyOut, func() uint64 { return cpu.cycles })
+	defer console.Flush()
+	cpu.inter.SetTTY(console)
+
+	if *audioDump != "" {
+		f, err := os.Create(*audioDump)
+		if err != nil {
+			fmt.Println("Error creating audio dump:", err)
+			return EXIT_ERROR
+		}
+		defer f.Close()
+
+		wav, err := spu.NewWAVSink(f)
+		if err != nil {
+			fmt.Println("Error writing audio dump:", err)
+			return EXIT_ERROR
+		}
+		cpu.inter.Spu().SetSink(wav)
+	} else if !*headless {
+		Open_audio(cpu.inter.Spu())
+	}
+	defer cpu.inter.Spu().Close()
+
+	var dbg *debugger.Debugger
+	if *debugMode {
+		dbg = debugger.New(os.Stdin, os.Stdout)
+		cpu.Attach_debugger(dbg)
+	}
+
+	if *tracePath != "" {
+		f, err := os.Create(*tracePath)
+		if err != nil {
+			fmt.Println("Error creating trace:", err)
+			return EXIT_ERROR
+		}
+		defer f.Close()
+
+		w, err := trace.NewWriter(f, trace.HAS_OPCODE|trace.HAS_MEMORY)
+		if err != nil {
+			fmt.Println("Error writing trace:", err)
+			return EXIT_ERROR
+		}
+		recorder := trace.NewRecorder(w)
+		defer func() {
+			if err := recorder.Close(); err != nil {
+				fmt.Println("Error writing trace:", err)
+			}
+		}()
+		cpu.Attach_tracer(recorder)
+	}
+
+	var stub *gdbstub.Stub
+	if *gdbAddr != "" {
+		stub, err = gdbstub.Listen(*gdbAddr)
+		if err != nil {
+			fmt.Println("Error starting GDB stub:", err)
+			return EXIT_ERROR
+		}
+		cpu.Attach_gdb(stub)
+	}
 	fmt.Println(cpu.reg[0])
 
-	for{
-		for i := 0; i < 1000000; i++ {
-			cpu.Run_next()
+	if *headless {
+		status := Run_headless(cpu, limits)
+		if *dumpVram != "" {
+			if err := Dump_vram(cpu, *dumpVram); err != nil {
+				fmt.Println("Error dumping VRAM:", err)
+				status = EXIT_ERROR
+			}
+		}
+		if *dumpFrame != "" {
+			if err := Dump_frame(cpu, *dumpFrame); err != nil {
+				fmt.Println("Error dumping frame:", err)
+				status = EXIT_ERROR
+			}
+		}
+		if *dumpRam != "" {
+			if err := Dump_ram(cpu, *dumpRam); err != nil {
+				fmt.Println("Error dumping RAM:", err)
+				status = EXIT_ERROR
+			}
+		}
+		if err := cpu.inter.Spu().Close(); err != nil {
+			fmt.Println("Error writing audio dump:", err)
+			status = EXIT_ERROR
 		}
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-            switch event.(type) {
-            case *sdl.QuitEvent:
-                return
-            }
-        }
+		return status
 	}
+
+	return Run_windowed(cpu, dbg, stub, bindings, pads)
 }
 
-func CheckForErrors() {
-    fatal := false
-
-    for {
-        buffer := make([]byte, 4096)
-        severity := uint32(0)
-        source := uint32(0)
-        mSize := int32(0)
-        mtype := uint32(0)
-        id := uint32(0)
-        count := gl.GetDebugMessageLog(1, int32(len(buffer)), &source, &mtype, &id, &severity, &mSize, &buffer[0])
-
-        if count == 0 {
-            break
-        }
-
-        // Assuming mSize is the actual message length, trim the buffer to mSize
-        message := string(buffer[:mSize])
-
-        fmt.Printf("OpenGL [source: %d | type: %d | id: 0x%x | severity: %d] %s\n", source, mtype, id, severity, message)
-
-        // Example severity check (adjust according to your severity values)
-        if severity == gl.DEBUG_SEVERITY_HIGH {
-            fatal = true
-            fmt.Println("Fatal OpenGL error encountered.")
-            break // or handle fatal error as needed
-        }
-    }
-
-    if fatal {
-        // Handle fatal error, e.g., clean up and exit or throw panic
-        panic("Fatal OpenGL error encountered.")
-    }
-}
\ No newline at end of file
+func Run_guarded(cpu *CPU, dbg *debugger.Debugger) { //Panics land in the debugger
+	defer func() {
+		if err := recover(); err != nil {
+			dbg.Panic(cpu, err, debug.Stack())
+		}
+	}()
+	cpu.Run_next()
+}
@@ -9,6 +9,10 @@ func (r *RAM) New() RAM {
 	return RAM{data: data}
 }
 
+func (r *RAM) Bytes() []uint8 { //Backing memory, for dumps
+	return r.data
+}
+
 func (r *RAM) Load32(offset uint32) uint32 { //Fetch word at offset
 	b0 := uint32(r.data[offset])
 	b1 := uint32(r.data[offset+1])
@@ -52,3 +56,7 @@ func (r *RAM) Store16(offset uint32, val uint16) { //Store val into offset
 func (r *RAM) Store8(offset uint32, val uint8) { //Store val into offset
 	r.data[offset] = val
 }
+
+func (r *RAM) Write(offset uint32, data []uint8) { //Bulk copy into RAM at offset
+	copy(r.data[offset:], data)
+}
@@ -0,0 +1,210 @@
+package sio
+
+// Config mode commands, only answered after CMD_CONFIG with argument 1
+const (
+	CMD_SET_MODE      uint8 = 0x44
+	CMD_STATUS        uint8 = 0x45
+	CMD_CONSTANT_1    uint8 = 0x46
+	CMD_CONSTANT_2    uint8 = 0x47
+	CMD_CONSTANT_3    uint8 = 0x4c
+	CMD_RUMBLE_CONFIG uint8 = 0x4d
+)
+
+// Values in the rumble mapping set by CMD_RUMBLE_CONFIG
+const (
+	RUMBLE_SMALL uint8 = 0x00 //This poll byte switches the small motor, bit 0
+	RUMBLE_LARGE uint8 = 0x01 //This poll byte is the large motor's speed
+	RUMBLE_NONE  uint8 = 0xff
+)
+
+type Axis uint8
+
+const (
+	RightX Axis = iota
+	RightY
+	LeftX
+	LeftY
+)
+
+const STICK_CENTRE uint8 = 0x80
+
+// SCPH-1200 DualShock. Starts digital, the analog button or CMD_SET_MODE switches it to
+// analog, which adds the sticks to polls and lets the rumble mapping drive the motors.
+type DualShock struct {
+	buttons uint16 //Pressed buttons, bit set when held
+	sticks  [4]uint8
+	analog  bool
+	locked  bool //The analog button is ignored
+	config  bool
+	rumble  [6]uint8 //Poll argument bytes to motors
+	small   uint8
+	large   uint8
+	index   int //Byte within the packet, -1 when not addressed
+	command uint8
+	reply   []uint8 //From the ID byte on
+	args    [6]uint8
+}
+
+func NewDualShock() *DualShock {
+	p := &DualShock{index: -1}
+	for i := range p.sticks {
+		p.sticks[i] = STICK_CENTRE
+	}
+	for i := range p.rumble {
+		p.rumble[i] = RUMBLE_NONE
+	}
+	return p
+}
+
+func (p *DualShock) SetButton(button Button, pressed bool) {
+	if pressed {
+		p.buttons |= 1 << button
+	} else {
+		p.buttons &^= 1 << button
+	}
+}
+
+func (p *DualShock) SetAxis(axis Axis, val uint8) {
+	p.sticks[axis] = val
+}
+
+func (p *DualShock) ToggleAnalog() { //The analog button
+	if !p.locked {
+		p.analog = !p.analog
+	}
+}
+
+func (p *DualShock) Analog() bool {
+	return p.analog
+}
+
+func (p *DualShock) Motors() (small uint8, large uint8) { //Last levels the game asked for
+	return p.small, p.large
+}
+
+func (p *DualShock) Select() {
+	p.index = 0
+	p.args = [6]uint8{}
+}
+
+func (p *DualShock) Exchange(tx uint8) (uint8, bool) {
+	if p.index < 0 {
+		return PAD_HIZ, false
+	}
+
+	i := p.index
+	p.index++
+	switch {
+	case i == 0:
+		if tx != PAD_ADDRESS {
+			p.index = -1
+			return PAD_HIZ, false
+		}
+		return PAD_HIZ, true
+	case i == 1:
+		if !p.accepts(tx) {
+			p.index = -1
+			return PAD_HIZ, false
+		}
+		p.command = tx
+		p.reply = p.response(tx)
+		return p.reply[0], true
+	}
+
+	if i >= 3 && i-3 < len(p.args) {
+		p.argument(i-3, tx)
+	}
+	n := i - 1
+	rx := p.reply[n]
+	if n < len(p.reply)-1 {
+		return rx, true
+	}
+	p.finish()
+	p.index = -1
+	return rx, false
+}
+
+func (p *DualShock) accepts(command uint8) bool {
+	if p.config {
+		return command >= 0x40 && command <= 0x4f
+	}
+	return command == CMD_POLL || command == CMD_CONFIG
+}
+
+func (p *DualShock) id() uint8 {
+	switch {
+	case p.config:
+		return ID_CONFIG
+	case p.analog:
+		return ID_ANALOG
+	default:
+		return ID_DIGITAL
+	}
+}
+
+func (p *DualShock) response(command uint8) []uint8 { //ID, 0x5a, then the data bytes
+	reply := []uint8{p.id(), PAD_DATA}
+
+	switch command {
+	case CMD_POLL, CMD_CONFIG:
+		reply = append(reply, ^uint8(p.buttons), ^uint8(p.buttons>>8))
+		if p.analog || p.config {
+			reply = append(reply, p.sticks[:]...)
+		}
+	case CMD_STATUS:
+		analog := uint8(0)
+		if p.analog {
+			analog = 1
+		}
+		reply = append(reply, 0x03, 0x02, analog, 0x02, 0x01, 0x00)
+	case CMD_CONSTANT_1: //Second half picked by the argument
+		reply = append(reply, 0x00, 0x00, 0x01, 0x02, 0x00, 0x0a)
+	case CMD_CONSTANT_2:
+		reply = append(reply, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00)
+	case CMD_CONSTANT_3:
+		reply = append(reply, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00)
+	case CMD_RUMBLE_CONFIG: //The old mapping
+		reply = append(reply, p.rumble[:]...)
+	default:
+		reply = append(reply, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	}
+	return reply
+}
+
+func (p *DualShock) argument(n int, tx uint8) { //Byte n after the 0x5a
+	p.args[n] = tx
+	if n != 0 || tx != 1 {
+		return
+	}
+
+	switch p.command {
+	case CMD_CONSTANT_1:
+		copy(p.reply[5:], []uint8{0x01, 0x01, 0x14})
+	case CMD_CONSTANT_3:
+		p.reply[5] = 0x07
+	}
+}
+
+func (p *DualShock) finish() { //Whole packet received
+	switch p.command {
+	case CMD_POLL:
+		for n, motor := range p.rumble {
+			switch motor {
+			case RUMBLE_SMALL:
+				p.small = 0
+				if p.args[n]&1 != 0 {
+					p.small = 0xff
+				}
+			case RUMBLE_LARGE:
+				p.large = p.args[n]
+			}
+		}
+	case CMD_CONFIG:
+		p.config = p.args[0] == 1
+	case CMD_SET_MODE:
+		p.analog = p.args[0] == 1
+		p.locked = p.args[1] == 3
+	case CMD_RUMBLE_CONFIG:
+		p.rumble = p.args
+	}
+}
@@ -0,0 +1,88 @@
+package sio
+
+// Pad buttons, bit positions in the two button bytes. The pad sends them active low.
+type Button uint8
+
+const (
+	Select Button = iota
+	L3
+	R3
+	Start
+	Up
+	Right
+	Down
+	Left
+	L2
+	R2
+	L1
+	R1
+	Triangle
+	Circle
+	Cross
+	Square
+)
+
+// Controller packet bytes
+const (
+	PAD_ADDRESS uint8 = 0x01 //First byte of a packet for a pad, 0x81 addresses memory cards
+	PAD_HIZ     uint8 = 0xff //Reply while nothing has been said yet
+	PAD_DATA    uint8 = 0x5a //Reply to the byte after the command
+	ID_DIGITAL  uint8 = 0x41
+	ID_ANALOG   uint8 = 0x73
+	ID_CONFIG   uint8 = 0xf3
+	CMD_POLL    uint8 = 0x42
+	CMD_CONFIG  uint8 = 0x43
+)
+
+// Something with buttons, for the input mapping
+type Pad interface {
+	Device
+	SetButton(button Button, pressed bool)
+}
+
+// SCPH-1080 digital pad, answers 0x42 polls with the two button bytes
+type DigitalPad struct {
+	buttons uint16 //Pressed buttons, bit set when held
+	index   int    //Byte within the packet, -1 when not addressed
+}
+
+func NewDigitalPad() *DigitalPad {
+	return &DigitalPad{index: -1}
+}
+
+func (p *DigitalPad) SetButton(button Button, pressed bool) {
+	if pressed {
+		p.buttons |= 1 << button
+	} else {
+		p.buttons &^= 1 << button
+	}
+}
+
+func (p *DigitalPad) Select() {
+	p.index = 0
+}
+
+func (p *DigitalPad) Exchange(tx uint8) (uint8, bool) {
+	if p.index < 0 {
+		return PAD_HIZ, false
+	}
+
+	i := p.index
+	p.index++
+	switch {
+	case i == 0 && tx == PAD_ADDRESS:
+		return PAD_HIZ, true
+	case i == 1 && tx == CMD_POLL:
+		return ID_DIGITAL, true
+	case i == 2:
+		return PAD_DATA, true
+	case i == 3:
+		return ^uint8(p.buttons), true
+	case i == 4: //Last byte, no /ACK
+		p.index = -1
+		return ^uint8(p.buttons >> 8), false
+	default: //Not for us, stay quiet until the next select
+		p.index = -1
+		return PAD_HIZ, false
+	}
+}
@@ -0,0 +1,225 @@
+package sio
+
+import (
+	"github.com/Koops0/GPSXE/irq"
+)
+
+// Delays in CPU cycles
+const (
+	ACK_DELAY  uint32 = 338 //From the end of a byte to the device pulling /ACK low
+	ACK_LENGTH uint32 = 100 //How long /ACK stays low
+)
+
+// JOY_CTRL bits
+const (
+	CTRL_TX_ENABLE  uint16 = 0x0001
+	CTRL_SELECT     uint16 = 0x0002 //JOYn output, selects the device on the chosen port
+	CTRL_ACK        uint16 = 0x0010 //Write only, clears the IRQ and error flags
+	CTRL_RESET      uint16 = 0x0040 //Write only
+	CTRL_ACK_IRQ    uint16 = 0x1000
+	CTRL_PORT       uint16 = 0x2000 //0 for port 1, 1 for port 2
+	CTRL_WRITE_MASK uint16 = 0x3f2f
+)
+
+// JOY_STAT bits
+const (
+	STAT_TX_READY uint32 = 0x001
+	STAT_RX_READY uint32 = 0x002
+	STAT_TX_DONE  uint32 = 0x004
+	STAT_ACK      uint32 = 0x080 //The /ACK input is low
+	STAT_IRQ      uint32 = 0x200
+)
+
+// Baud rate reload factors from JOY_MODE bits 0-1
+var BAUD_FACTOR = [4]uint32{1, 1, 16, 64}
+
+// Something plugged into a controller port: a pad, or later a memory card
+type Device interface {
+	Select()                                //Start of a new packet
+	Exchange(tx uint8) (rx uint8, ack bool) //One byte each way, ack when the device wants another
+}
+
+// Controller and memory card serial port at 0x1f801040
+type SIO0 struct {
+	ports     [2]Device
+	ctrl      uint16
+	mode      uint16
+	baud      uint16
+	tx        uint8
+	txPending bool //Written but not sent yet
+	rx        uint8
+	rxReady   bool
+	transfer  uint32 //Cycles until the byte being sent is done, 0 when idle
+	ackDelay  uint32 //Cycles until /ACK goes low
+	ackLow    uint32 //Cycles /ACK stays low
+	irqFlag   bool
+	irq       *irq.InterruptState
+}
+
+func New(irq *irq.InterruptState) *SIO0 {
+	return &SIO0{irq: irq}
+}
+
+func (s *SIO0) Plug(port int, device Device) { //nil unplugs
+	s.ports[port] = device
+}
+
+func (s *SIO0) Port(port int) Device {
+	return s.ports[port]
+}
+
+func (s *SIO0) Load(offset uint32) uint32 {
+	switch offset {
+	case 0x0:
+		return uint32(s.ReadData())
+	case 0x4:
+		return s.Status()
+	case 0x8:
+		return uint32(s.mode)
+	case 0xa:
+		return uint32(s.ctrl)
+	case 0xe:
+		return uint32(s.baud)
+	default:
+		return 0
+	}
+}
+
+func (s *SIO0) Store(offset uint32, val uint32) {
+	switch offset {
+	case 0x0:
+		s.WriteData(uint8(val))
+	case 0x8:
+		s.mode = uint16(val)
+	case 0xa:
+		s.SetCtrl(uint16(val))
+	case 0xe:
+		s.baud = uint16(val)
+	}
+}
+
+func (s *SIO0) ReadData() uint8 { //Pops the received byte, 0xff when there is none
+	if !s.rxReady {
+		return 0xff
+	}
+	s.rxReady = false
+	return s.rx
+}
+
+func (s *SIO0) WriteData(val uint8) {
+	s.tx = val
+	s.txPending = true
+	s.startTransfer()
+}
+
+func (s *SIO0) Status() uint32 {
+	var stat uint32
+	if !s.txPending {
+		stat |= STAT_TX_READY
+	}
+	if s.rxReady {
+		stat |= STAT_RX_READY
+	}
+	if !s.txPending && s.transfer == 0 {
+		stat |= STAT_TX_DONE
+	}
+	if s.ackLow > 0 {
+		stat |= STAT_ACK
+	}
+	if s.irqFlag {
+		stat |= STAT_IRQ
+	}
+	return stat
+}
+
+func (s *SIO0) SetCtrl(val uint16) {
+	if val&CTRL_RESET != 0 {
+		s.reset()
+		return
+	}
+	if val&CTRL_ACK != 0 {
+		s.irqFlag = false
+	}
+
+	before := s.selected()
+	s.ctrl = val & CTRL_WRITE_MASK
+	if after := s.selected(); after != nil && after != before {
+		after.Select()
+	}
+	if s.ctrl&CTRL_SELECT == 0 { //Deselected, whatever was in flight is lost
+		s.ackDelay = 0
+	}
+	s.startTransfer()
+}
+
+func (s *SIO0) reset() {
+	s.ctrl = 0
+	s.mode = 0
+	s.baud = 0
+	s.txPending = false
+	s.rxReady = false
+	s.transfer = 0
+	s.ackDelay = 0
+	s.ackLow = 0
+	s.irqFlag = false
+}
+
+func (s *SIO0) selected() Device { //Device on the chosen port while JOYn is asserted
+	if s.ctrl&CTRL_SELECT == 0 {
+		return nil
+	}
+	if s.ctrl&CTRL_PORT != 0 {
+		return s.ports[1]
+	}
+	return s.ports[0]
+}
+
+func (s *SIO0) startTransfer() {
+	if !s.txPending || s.transfer != 0 || s.ctrl&CTRL_TX_ENABLE == 0 {
+		return
+	}
+	s.txPending = false
+	s.transfer = max(uint32(s.baud)*BAUD_FACTOR[s.mode&3]*8, 1)
+}
+
+func (s *SIO0) finishTransfer() {
+	s.rx = 0xff //Nothing drives the line
+	ack := false
+	if device := s.selected(); device != nil {
+		s.rx, ack = device.Exchange(s.tx)
+	}
+	s.rxReady = true
+
+	if ack {
+		s.ackDelay = ACK_DELAY
+	}
+	s.startTransfer()
+}
+
+func (s *SIO0) Tick(cycles uint32) {
+	if s.transfer > 0 {
+		if cycles >= s.transfer {
+			s.transfer = 0
+			s.finishTransfer()
+		} else {
+			s.transfer -= cycles
+		}
+	}
+
+	if s.ackLow > 0 {
+		s.ackLow -= min(cycles, s.ackLow)
+	}
+
+	if s.ackDelay > 0 {
+		if cycles < s.ackDelay {
+			s.ackDelay -= cycles
+			return
+		}
+		s.ackDelay = 0
+		s.ackLow = ACK_LENGTH
+		if s.ctrl&CTRL_ACK_IRQ != 0 && !s.irqFlag {
+			s.irqFlag = true
+			s.irq.Assert(irq.PadMemCard)
+		}
+	}
+}
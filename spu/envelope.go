@@ -0,0 +1,167 @@
+package spu
+
+const (
+	ENVELOPE_MIN = 0
+	ENVELOPE_MAX = 0x7fff
+)
+
+// Volume ramp shared by ADSR phases and volume sweeps. The rate packs a shift in bits 2-6 and a
+// step in bits 0-1, the level moves by step every time the counter crosses 0x8000.
+type Envelope struct {
+	rate        uint8
+	decreasing  bool
+	exponential bool
+	counter     uint32
+	increment   uint32
+	step        int32
+}
+
+// Rates below 44 take bigger steps, rates from 48 up tick less often. Rates whose bits under
+// mask are all set stall the envelope for good.
+func (e *Envelope) Reset(rate uint8, mask uint8, decreasing bool, exponential bool, invert bool) {
+	e.rate = rate
+	e.decreasing = decreasing
+	e.exponential = exponential
+	e.counter = 0
+	e.increment = 0x8000
+
+	base := int32(7 - rate&3)
+	if decreasing != invert || (decreasing && exponential) {
+		e.step = ^base
+	} else {
+		e.step = base
+	}
+
+	if rate < 44 {
+		e.step <<= 11 - rate>>2
+	} else if rate >= 48 {
+		e.increment >>= rate>>2 - 11
+		if rate&mask != mask {
+			e.increment = max(e.increment, 1)
+		}
+	}
+}
+
+func (e *Envelope) Tick(level int16) int16 {
+	increment := e.increment
+	step := e.step
+
+	if e.exponential {
+		if e.decreasing { //Steps shrink with the level
+			step = step * int32(level) >> 15
+		} else if level >= 0x6000 { //Slower near the top
+			switch {
+			case e.rate < 40:
+				step >>= 2
+			case e.rate >= 44:
+				increment >>= 2
+			default:
+				step >>= 1
+				increment >>= 1
+			}
+		}
+	}
+
+	e.counter += increment
+	if e.counter&0x8000 == 0 {
+		return level
+	}
+	e.counter = 0
+
+	return int16(min(max(int32(level)+step, ENVELOPE_MIN), ENVELOPE_MAX))
+}
+
+type ADSRPhase uint8
+
+const (
+	Off ADSRPhase = iota
+	Attack
+	Decay
+	Sustain
+	Release
+)
+
+// Attack, decay, sustain and release from the voice's 32 bit ADSR register
+type ADSR struct {
+	config   uint32
+	phase    ADSRPhase
+	level    int16
+	envelope Envelope
+}
+
+func (a *ADSR) SetPhase(phase ADSRPhase) {
+	a.phase = phase
+	c := a.config
+
+	switch phase {
+	case Attack:
+		a.envelope.Reset(uint8(c>>8)&0x7f, 0x7f, false, c&0x8000 != 0, false)
+	case Decay:
+		a.envelope.Reset(uint8(c>>4)&0xf<<2, 0x7c, true, true, false)
+	case Sustain:
+		a.envelope.Reset(uint8(c>>22)&0x7f, 0x7f, c&(1<<30) != 0, c&(1<<31) != 0, false)
+	case Release:
+		a.envelope.Reset(uint8(c>>16)&0x1f<<2, 0x7c, true, c&(1<<21) != 0, false)
+	}
+}
+
+func (a *ADSR) sustainLevel() int16 {
+	return int16(min((a.config&0xf+1)*0x800, ENVELOPE_MAX))
+}
+
+func (a *ADSR) Tick() {
+	if a.phase == Off {
+		return
+	}
+
+	a.level = a.envelope.Tick(a.level)
+
+	switch a.phase {
+	case Attack:
+		if a.level >= ENVELOPE_MAX {
+			a.SetPhase(Decay)
+		}
+	case Decay:
+		if a.level <= a.sustainLevel() {
+			a.SetPhase(Sustain)
+		}
+	case Release:
+		if a.level <= ENVELOPE_MIN {
+			a.phase = Off
+		}
+	}
+}
+
+// Voice or main volume register, a fixed level or a sweep when bit 15 is set
+type Volume struct {
+	reg      uint16
+	level    int16
+	sweeping bool
+	envelope Envelope
+}
+
+func (v *Volume) Set(val uint16) {
+	v.reg = val
+
+	if val&0x8000 == 0 { //Bits 0-14 are half the level
+		v.level = int16(val << 1)
+		v.sweeping = false
+		return
+	}
+
+	v.envelope.Reset(uint8(val&0x7f), 0x7f, val&0x2000 != 0, val&0x4000 != 0, val&0x1000 != 0)
+	v.sweeping = v.envelope.increment > 0
+}
+
+func (v *Volume) Tick() {
+	if !v.sweeping {
+		return
+	}
+
+	v.level = v.envelope.Tick(v.level)
+	if v.envelope.decreasing {
+		v.sweeping = v.level > ENVELOPE_MIN
+	} else {
+		v.sweeping = v.level < ENVELOPE_MAX
+	}
+}
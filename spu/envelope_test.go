@@ -0,0 +1,75 @@
+package spu
+
+import (
+	"testing"
+)
+
+// Ticks until the ADSR leaves phase, at most limit
+func ticksIn(a *ADSR, phase ADSRPhase, limit int) int {
+	n := 0
+	for a.phase == phase && n < limit {
+		a.Tick()
+		n++
+	}
+	return n
+}
+
+func TestAttack(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		config uint32
+		ticks  int
+	}{
+		{"linear rate 0, +7<<11 a sample", 0x00000000, 3},
+		{"linear rate 44, +7 a sample", 44 << 8, 4681},
+		{"linear rate 48, +7 every 2 samples", 48 << 8, 9362},
+		{"linear rate 47, +4 a sample", 47 << 8, 8192},
+		{"exponential rate 36, a quarter of the step from 0x6000", 0x8000 | 36<<8, 878 + 1169},
+		{"exponential rate 40, half the step at half the speed", 0x8000 | 40<<8, 1756 + 1169*2},
+		{"exponential rate 44, 4 samples a step from 0x6000", 0x8000 | 44<<8, 3511 + 1170*4},
+	} {
+		a := ADSR{config: c.config}
+		a.SetPhase(Attack)
+		if got := ticksIn(&a, Attack, 1<<20); got != c.ticks {
+			t.Errorf("%s: %d ticks, want %d", c.name, got, c.ticks)
+		}
+		if a.level != ENVELOPE_MAX || a.phase != Decay {
+			t.Errorf("%s: ended at 0x%04x in phase %d", c.name, a.level, a.phase)
+		}
+	}
+}
+
+func TestDecayAndSustainLevel(t *testing.T) {
+	a := ADSR{config: 11<<4 | 7, level: ENVELOPE_MAX} //Decay shift 11, sustain at 0x4000
+	a.SetPhase(Decay)
+
+	a.Tick()
+	if a.level != 0x7ff7 { //-8 scaled by the level, floored
+		t.Errorf("first decay step to 0x%04x, want 0x7ff7", a.level)
+	}
+
+	ticksIn(&a, Decay, 1<<20)
+	if a.phase != Sustain || a.level > 0x4000 || a.level < 0x4000-8 {
+		t.Errorf("decay stopped at 0x%04x in phase %d, want just under 0x4000 in sustain", a.level, a.phase)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	a := ADSR{level: ENVELOPE_MAX} //Release shift 0 linear, -8<<11 a sample
+	a.SetPhase(Release)
+	if got := ticksIn(&a, Release, 100); got != 2 || a.level != 0 {
+		t.Errorf("released in %d ticks to 0x%04x, want 2 ticks to 0", got, a.level)
+	}
+}
+
+func TestEnvelopeStalls(t *testing.T) {
+	var e Envelope
+	e.Reset(0x7f, 0x7f, false, false, false) //Every rate bit set
+	level := int16(0x1234)
+	for i := 0; i < 1<<16; i++ {
+		level = e.Tick(level)
+	}
+	if level != 0x1234 {
+		t.Errorf("rate 0x7f moved the level to 0x%04x", level)
+	}
+}
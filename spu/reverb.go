@@ -0,0 +1,158 @@
+package spu
+
+// Reverb registers from 0x1c0, named as in the documentation. d* and m* are addresses in
+// 8 byte units relative to the buffer address, v* are signed volumes.
+const (
+	dAPF1 = iota
+	dAPF2
+	vIIR
+	vCOMB1
+	vCOMB2
+	vCOMB3
+	vCOMB4
+	vWALL
+	vAPF1
+	vAPF2
+	mLSAME
+	mRSAME
+	mLCOMB1
+	mRCOMB1
+	mLCOMB2
+	mRCOMB2
+	dLSAME
+	dRSAME
+	mLDIFF
+	mRDIFF
+	mLCOMB3
+	mRCOMB3
+	mLCOMB4
+	mRCOMB4
+	dLDIFF
+	dRDIFF
+	mLAPF1
+	mRAPF1
+	mLAPF2
+	mRAPF2
+	vLIN
+	vRIN
+)
+
+// Half band filter taking the reverb input down to 22.05kHz and its output back up
+var REVERB_FIR = [39]int32{
+	-0x0001, 0x0000, 0x0002, 0x0000, -0x000a, 0x0000, 0x0023, 0x0000,
+	-0x0067, 0x0000, 0x010a, 0x0000, -0x0268, 0x0000, 0x0534, 0x0000,
+	-0x0b90, 0x0000, 0x2806, 0x4000, 0x2806, 0x0000, -0x0b90, 0x0000,
+	0x0534, 0x0000, -0x0268, 0x0000, 0x010a, 0x0000, -0x0067, 0x0000,
+	0x0023, 0x0000, -0x000a, 0x0000, 0x0002, 0x0000, -0x0001,
+}
+
+const REVERB_HISTORY = 64 //Power of two above the filter length
+
+type Reverb struct {
+	pos   uint32                   //Buffer address in bytes, moves through the work area
+	in    [2][REVERB_HISTORY]int32 //44.1kHz input
+	out   [2][REVERB_HISTORY]int32 //44.1kHz output, zero between 22.05kHz results
+	index int
+	odd   bool //The work area only runs every other sample
+}
+
+func (s *SPU) reverbReg(reg int) uint16 {
+	return s.regs[0x1c0>>1+reg]
+}
+
+func (s *SPU) reverbBase() uint32 { //mBASE
+	return uint32(s.regs[0x1a2>>1]) * 8
+}
+
+func (s *SPU) reverbAddr(offset int32) uint32 { //Wraps inside the work area, mBASE to the end of RAM
+	base := s.reverbBase()
+	size := int32(RAM_SIZE - base)
+	rel := (int32(s.reverb.pos-base) + offset) % size
+	if rel < 0 {
+		rel += size
+	}
+	return (base + uint32(rel)) &^ 1
+}
+
+func (s *SPU) reverbLoad(offset int32) int32 {
+	addr := s.reverbAddr(offset)
+	s.checkIRQ(addr, 2)
+	return int32(int16(uint16(s.ram[addr]) | uint16(s.ram[addr+1])<<8))
+}
+
+func (s *SPU) reverbStore(offset int32, val int32) {
+	addr := s.reverbAddr(offset)
+	s.checkIRQ(addr, 2)
+	v := clamp16(val)
+	s.ram[addr] = uint8(v)
+	s.ram[addr+1] = uint8(v >> 8)
+}
+
+// Takes the 44.1kHz reverb input, returns the reverb output before vLOUT and vROUT
+func (s *SPU) processReverb(left int32, right int32) (int32, int32) {
+	r := &s.reverb
+	r.in[0][r.index] = left
+	r.in[1][r.index] = right
+	r.out[0][r.index] = 0
+	r.out[1][r.index] = 0
+
+	if r.odd {
+		l, rr := s.reverbStep(r.filter(&r.in[0], 1), r.filter(&r.in[1], 1))
+		r.out[0][r.index] = l
+		r.out[1][r.index] = rr
+	}
+	r.odd = !r.odd
+
+	outLeft := clamp16(r.filter(&r.out[0], 2))
+	outRight := clamp16(r.filter(&r.out[1], 2))
+	r.index = (r.index + 1) & (REVERB_HISTORY - 1)
+	return outLeft, outRight
+}
+
+func (r *Reverb) filter(history *[REVERB_HISTORY]int32, gain int32) int32 { //Gain makes up for the zeros
+	var sum int32
+	for k, tap := range REVERB_FIR {
+		sum += tap * history[(r.index-len(REVERB_FIR)+1+k)&(REVERB_HISTORY-1)]
+	}
+	return sum * gain >> 15
+}
+
+func (s *SPU) reverbStep(left int32, right int32) (int32, int32) { //One 22.05kHz step over the work area
+	v := func(reg int) int32 { return int32(int16(s.reverbReg(reg))) }
+	m := func(reg int) int32 { return int32(s.reverbReg(reg)) * 8 }
+	mul := func(a int32, b int32) int32 { return a * b >> 15 }
+
+	lin := mul(clamp16(left), v(vLIN))
+	rin := mul(clamp16(right), v(vRIN))
+
+	if s.control&CNT_REVERB != 0 { //Reflections are only written back when enabled
+		reflect := func(dst int, in int32, src int) {
+			prev := s.reverbLoad(m(dst) - 2)
+			x := clamp16(in + mul(s.reverbLoad(m(src)), v(vWALL)) - prev)
+			s.reverbStore(m(dst), mul(x, v(vIIR))+prev)
+		}
+		reflect(mLSAME, lin, dLSAME)
+		reflect(mRSAME, rin, dRSAME)
+		reflect(mLDIFF, lin, dRDIFF)
+		reflect(mRDIFF, rin, dLDIFF)
+	}
+
+	lout := mul(v(vCOMB1), s.reverbLoad(m(mLCOMB1))) + mul(v(vCOMB2), s.reverbLoad(m(mLCOMB2))) +
+		mul(v(vCOMB3), s.reverbLoad(m(mLCOMB3))) + mul(v(vCOMB4), s.reverbLoad(m(mLCOMB4)))
+	rout := mul(v(vCOMB1), s.reverbLoad(m(mRCOMB1))) + mul(v(vCOMB2), s.reverbLoad(m(mRCOMB2))) +
+		mul(v(vCOMB3), s.reverbLoad(m(mRCOMB3))) + mul(v(vCOMB4), s.reverbLoad(m(mRCOMB4)))
+
+	allPass := func(in int32, dst int, delay int, vol int) int32 {
+		delayed := s.reverbLoad(m(dst) - m(delay))
+		out := clamp16(in - mul(v(vol), delayed))
+		if s.control&CNT_REVERB != 0 {
+			s.reverbStore(m(dst), out)
+		}
+		return clamp16(mul(out, v(vol)) + delayed)
+	}
+	lout = allPass(allPass(lout, mLAPF1, dAPF1, vAPF1), mLAPF2, dAPF2, vAPF2)
+	rout = allPass(allPass(rout, mRAPF1, dAPF1, vAPF1), mRAPF2, dAPF2, vAPF2)
+
+	s.reverb.pos = max(s.reverbBase(), (s.reverb.pos+2)&(RAM_SIZE-2))
+	return lout, rout
+}
@@ -0,0 +1,71 @@
+package sdlaudio
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/Koops0/GPSXE/spu"
+)
+
+const (
+	SDL_TARGET_QUEUE = 2048 //Frames kept queued, about 46ms
+	SDL_MAX_QUEUE    = 8192 //The emulator is running ahead, drop chunks until the device catches up
+	MAX_RATE_ADJUST  = 0.005
+)
+
+// Queues samples on the default SDL audio device. The emulator and the sound card clocks drift
+// apart, so chunks are stretched or squeezed by up to half a percent to hold the queue steady.
+type SDLSink struct {
+	device sdl.AudioDeviceID
+	pos    float64  //Resampling position within the chunk
+	last   [2]int16 //Final frame of the previous chunk
+	buf    []byte
+}
+
+func NewSDLSink() (*SDLSink, error) {
+	spec := sdl.AudioSpec{
+		Freq:     int32(spu.SAMPLE_RATE),
+		Format:   sdl.AUDIO_S16LSB,
+		Channels: 2,
+		Samples:  spu.SINK_CHUNK,
+	}
+	device, err := sdl.OpenAudioDevice("", false, &spec, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	sdl.PauseAudioDevice(device, false)
+	return &SDLSink{device: device}, nil
+}
+
+func (s *SDLSink) Push(samples []int16) {
+	frames := len(samples) / 2
+	queued := int(sdl.GetQueuedAudioSize(s.device) / 4)
+	if queued > SDL_MAX_QUEUE || frames == 0 {
+		return
+	}
+
+	drift := min(max(float64(SDL_TARGET_QUEUE-queued)/SDL_TARGET_QUEUE, -1), 1)
+	step := 1 / (1 + drift*MAX_RATE_ADJUST) //Under the target, more frames come out than went in
+
+	s.buf = s.buf[:0]
+	for ; s.pos < float64(frames); s.pos += step { //Linear between the previous frame and this one
+		i := int(s.pos)
+		frac := s.pos - float64(i)
+		for c := 0; c < 2; c++ {
+			prev := s.last[c]
+			if i > 0 {
+				prev = samples[(i-1)*2+c]
+			}
+			v := float64(prev) + (float64(samples[i*2+c])-float64(prev))*frac
+			s.buf = append(s.buf, uint8(int16(v)), uint8(int16(v)>>8))
+		}
+	}
+	s.pos -= float64(frames)
+	s.last = [2]int16{samples[len(samples)-2], samples[len(samples)-1]}
+
+	sdl.QueueAudio(s.device, s.buf)
+}
+
+func (s *SDLSink) Close() error {
+	sdl.CloseAudioDevice(s.device)
+	return nil
+}
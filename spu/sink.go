@@ -0,0 +1,31 @@
+package spu
+
+const SINK_CHUNK = 512 //Stereo frames handed over at once
+
+// Where the mixed output goes, 44.1kHz stereo samples interleaved left and right.
+// Push may keep the slice only until it returns. Errors are held until Close.
+type Sink interface {
+	Push(samples []int16)
+	Close() error
+}
+
+func (s *SPU) SetSink(sink Sink) {
+	s.sink = sink
+}
+
+func (s *SPU) Flush() { //Hands over a partial chunk
+	if s.sink != nil && len(s.output) > 0 {
+		s.sink.Push(s.output)
+	}
+	s.output = s.output[:0]
+}
+
+func (s *SPU) Close() error { //Flushes and closes the sink, safe to call again
+	if s.sink == nil {
+		return nil
+	}
+	s.Flush()
+	err := s.sink.Close()
+	s.sink = nil
+	return err
+}
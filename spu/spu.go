@@ -0,0 +1,354 @@
+package spu
+
+import (
+	"github.com/Koops0/GPSXE/irq"
+)
+
+const (
+	CPU_CLOCK     uint32 = 33868800
+	SAMPLE_RATE   uint32 = 44100
+	SAMPLE_CYCLES uint32 = CPU_CLOCK / SAMPLE_RATE
+	RAM_SIZE      uint32 = 512 * 1024
+	VOICE_COUNT   int    = 24
+	FIFO_SIZE     int    = 32 //Halfwords
+)
+
+// SPUCNT bits
+const (
+	CNT_ENABLE     uint16 = 0x8000
+	CNT_UNMUTE     uint16 = 0x4000
+	CNT_REVERB     uint16 = 0x0080
+	CNT_IRQ_ENABLE uint16 = 0x0040
+	CNT_DMA        uint16 = 0x0020
+)
+
+type TransferMode uint8
+
+const (
+	Stop TransferMode = iota
+	ManualWrite
+	DMAWrite
+	DMARead
+)
+
+// Sound processing unit at 0x1f801c00, 24 ADPCM voices playing from 512KB of sound RAM
+type SPU struct {
+	ram          []uint8
+	voices       [VOICE_COUNT]Voice
+	regs         [0x140]uint16 //Last value written, read back by registers without side effects
+	mainLeft     Volume
+	mainRight    Volume
+	control      uint16 //SPUCNT
+	endx         uint32 //Voices that reached a loop end since their key on
+	transferAddr uint32 //Bytes
+	fifo         []uint16
+	irqFlag      bool //SPUSTAT bit 6, until IRQs are disabled in SPUCNT
+	noiseLevel   uint16
+	noiseTimer   int32
+	reverb       Reverb
+	cycles       uint32  //Left over from the last sample
+	output       []int16 //Interleaved left and right, waiting for the sink
+	sink         Sink
+	irq          *irq.InterruptState
+}
+
+func New(irq *irq.InterruptState) *SPU {
+	return &SPU{
+		ram:    make([]uint8, RAM_SIZE),
+		fifo:   make([]uint16, 0, FIFO_SIZE),
+		output: make([]int16, 0, SINK_CHUNK*2),
+		irq:    irq,
+	}
+}
+
+func (s *SPU) Load16(offset uint32) uint16 {
+	switch {
+	case offset < 0x180:
+		if offset&0xf == 0xc { //Current ADSR level
+			return uint16(s.voices[offset>>4].adsr.level)
+		}
+	case offset >= 0x200 && offset < 0x260: //Current voice volumes
+		v := &s.voices[(offset-0x200)>>2]
+		if offset&2 == 0 {
+			return uint16(v.left.level)
+		}
+		return uint16(v.right.level)
+	}
+
+	switch offset {
+	case 0x19c:
+		return uint16(s.endx)
+	case 0x19e:
+		return uint16(s.endx >> 16)
+	case 0x1aa:
+		return s.control
+	case 0x1ae:
+		return s.Status()
+	case 0x1b8:
+		return uint16(s.mainLeft.level)
+	case 0x1ba:
+		return uint16(s.mainRight.level)
+	default:
+		return s.regs[offset>>1]
+	}
+}
+
+func (s *SPU) Store16(offset uint32, val uint16) {
+	s.regs[offset>>1] = val
+
+	if offset < 0x180 {
+		s.storeVoice(&s.voices[offset>>4], offset&0xf, val)
+		return
+	}
+
+	switch offset {
+	case 0x180:
+		s.mainLeft.Set(val)
+	case 0x182:
+		s.mainRight.Set(val)
+	case 0x188:
+		s.KeyOn(uint32(val))
+	case 0x18a:
+		s.KeyOn(uint32(val) << 16)
+	case 0x18c:
+		s.KeyOff(uint32(val))
+	case 0x18e:
+		s.KeyOff(uint32(val) << 16)
+	case 0x1a2: //Reverb work area moved, start over at its beginning
+		s.reverb.pos = s.reverbBase()
+	case 0x1a6:
+		s.transferAddr = uint32(val) * 8
+	case 0x1a8:
+		s.WriteFifo(val)
+	case 0x1aa:
+		s.SetControl(val)
+	}
+}
+
+func (s *SPU) storeVoice(v *Voice, reg uint32, val uint16) {
+	switch reg {
+	case 0x0:
+		v.left.Set(val)
+	case 0x2:
+		v.right.Set(val)
+	case 0x4:
+		v.pitch = val
+	case 0x6:
+		v.start = val
+	case 0x8:
+		v.adsr.config = v.adsr.config&0xffff0000 | uint32(val)
+	case 0xa:
+		v.adsr.config = v.adsr.config&0xffff | uint32(val)<<16
+	case 0xc:
+		v.adsr.level = int16(val)
+	case 0xe:
+		v.SetRepeat(val)
+	}
+}
+
+func (s *SPU) KeyOn(voices uint32) {
+	for i := range s.voices {
+		if voices&(1<<i) != 0 {
+			s.voices[i].KeyOn()
+			s.endx &^= 1 << i
+		}
+	}
+}
+
+func (s *SPU) KeyOff(voices uint32) {
+	for i := range s.voices {
+		if voices&(1<<i) != 0 {
+			s.voices[i].KeyOff()
+		}
+	}
+}
+
+func (s *SPU) SetControl(val uint16) {
+	s.control = val
+	if val&CNT_IRQ_ENABLE == 0 { //Acknowledge
+		s.irqFlag = false
+	}
+	if s.TransferMode() == ManualWrite {
+		s.flushFifo()
+	}
+}
+
+func (s *SPU) TransferMode() TransferMode {
+	return TransferMode(s.control >> 4 & 3)
+}
+
+func (s *SPU) Status() uint16 { //SPUSTAT, transfers finish at once so never busy
+	stat := s.control & 0x3f
+	if s.irqFlag {
+		stat |= 1 << 6
+	}
+	if s.control&CNT_DMA != 0 {
+		stat |= 1 << 7
+	}
+	switch s.TransferMode() {
+	case DMAWrite:
+		stat |= 1 << 8
+	case DMARead:
+		stat |= 1 << 9
+	}
+	return stat
+}
+
+func (s *SPU) WriteFifo(val uint16) { //Held until the transfer mode is set to manual write
+	if len(s.fifo) < FIFO_SIZE {
+		s.fifo = append(s.fifo, val)
+	}
+	if s.TransferMode() == ManualWrite {
+		s.flushFifo()
+	}
+}
+
+func (s *SPU) flushFifo() {
+	for _, val := range s.fifo {
+		s.writeRAM(val)
+	}
+	s.fifo = s.fifo[:0]
+}
+
+func (s *SPU) voiceMask(offset uint32) uint32 { //PMON, NON, EON and the like, one bit per voice over two registers
+	return uint32(s.regs[offset>>1]) | uint32(s.regs[offset>>1+1])<<16
+}
+
+// Raises the SPU IRQ when enabled and the IRQ address falls in the length bytes at addr
+func (s *SPU) checkIRQ(addr uint32, length uint32) {
+	if s.control&CNT_IRQ_ENABLE == 0 || s.irqFlag {
+		return
+	}
+
+	target := uint32(s.regs[0x1a4>>1]) * 8
+	if target >= addr && target < addr+length {
+		s.irqFlag = true
+		s.irq.Assert(irq.Spu)
+	}
+}
+
+func (s *SPU) writeRAM(val uint16) { //At the transfer address, which then moves on
+	s.checkIRQ(s.transferAddr, 2)
+	s.ram[s.transferAddr] = uint8(val)
+	s.ram[s.transferAddr+1] = uint8(val >> 8)
+	s.transferAddr = (s.transferAddr + 2) & (RAM_SIZE - 1)
+}
+
+func (s *SPU) readRAM() uint16 {
+	s.checkIRQ(s.transferAddr, 2)
+	val := uint16(s.ram[s.transferAddr]) | uint16(s.ram[s.transferAddr+1])<<8
+	s.transferAddr = (s.transferAddr + 2) & (RAM_SIZE - 1)
+	return val
+}
+
+func (s *SPU) DmaWrite(word uint32) { //DMA channel 4 from RAM
+	s.writeRAM(uint16(word))
+	s.writeRAM(uint16(word >> 16))
+}
+
+func (s *SPU) DmaRead() uint32 { //DMA channel 4 to RAM
+	lo := uint32(s.readRAM())
+	return lo | uint32(s.readRAM())<<16
+}
+
+func (s *SPU) RAM() []uint8 {
+	return s.ram
+}
+
+func (s *SPU) Tick(cycles uint32) { //One stereo sample every 768 CPU cycles
+	s.cycles += cycles
+	for s.cycles >= SAMPLE_CYCLES {
+		s.cycles -= SAMPLE_CYCLES
+		s.sample()
+	}
+}
+
+func (s *SPU) tickNoise() { //Shift register clocked by SPUCNT bits 8-13
+	step := int32(s.control>>8&3) + 4
+	shift := s.control >> 10 & 0xf
+	level := s.noiseLevel
+	parity := (level>>15 ^ level>>12 ^ level>>11 ^ level>>10 ^ 1) & 1
+
+	s.noiseTimer -= step
+	if s.noiseTimer < 0 {
+		s.noiseLevel = level<<1 | parity
+		s.noiseTimer += 0x20000 >> shift
+	}
+	if s.noiseTimer < 0 {
+		s.noiseTimer += 0x20000 >> shift
+	}
+}
+
+func (s *SPU) sample() {
+	var left, right int32
+	var reverbLeft, reverbRight int32
+
+	s.tickNoise()
+	pmon := s.voiceMask(0x190)
+	non := s.voiceMask(0x194)
+	eon := s.voiceMask(0x198)
+
+	for i := range s.voices {
+		v := &s.voices[i]
+		if !v.On() {
+			v.output = 0
+			continue
+		}
+
+		if !v.decoded {
+			s.checkIRQ(uint32(v.current)*8, BLOCK_SIZE)
+			v.decode(s.ram)
+		}
+
+		in := v.interpolate()
+		if non&(1<<i) != 0 { //Noise replaces the samples, which still play to drive the loop flags
+			in = int16(s.noiseLevel)
+		}
+
+		out := int32(in) * int32(v.adsr.level) >> 15
+		v.output = int16(out)
+		l := out * int32(v.left.level) >> 15
+		r := out * int32(v.right.level) >> 15
+		left += l
+		right += r
+		if eon&(1<<i) != 0 {
+			reverbLeft += l
+			reverbRight += r
+		}
+
+		step := uint32(v.pitch)
+		if i > 0 && pmon&(1<<i) != 0 { //Scaled by the previous voice, 0.0 to 2.0
+			factor := int32(s.voices[i-1].output) + 0x8000
+			step = uint32(int32(int16(v.pitch))*factor>>15) & 0xffff
+		}
+
+		v.left.Tick()
+		v.right.Tick()
+		v.adsr.Tick()
+		if v.advance(step) {
+			s.endx |= 1 << i
+		}
+	}
+
+	reverbLeft, reverbRight = s.processReverb(clamp16(reverbLeft), clamp16(reverbRight))
+	left += reverbLeft * int32(int16(s.regs[0x184>>1])) >> 15
+	right += reverbRight * int32(int16(s.regs[0x186>>1])) >> 15
+
+	left = clamp16(left) * int32(s.mainLeft.level) >> 15
+	right = clamp16(right) * int32(s.mainRight.level) >> 15
+	s.mainLeft.Tick()
+	s.mainRight.Tick()
+
+	if s.control&CNT_ENABLE == 0 || s.control&CNT_UNMUTE == 0 {
+		left, right = 0, 0
+	}
+
+	s.output = append(s.output, int16(left), int16(right))
+	if len(s.output) == SINK_CHUNK*2 {
+		s.Flush()
+	}
+}
+
+func clamp16(v int32) int32 {
+	return min(max(v, -0x8000), 0x7fff)
+}
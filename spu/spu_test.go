@@ -0,0 +1,59 @@
+package spu
+
+import (
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		control uint16
+		level   uint16
+		ticks   int
+		want    uint16
+	}{
+		{"fastest clocks every sample", 0xf<<10 | 3<<8, 0, 11, 0x7ff},
+		{"tap 10", 0xf<<10 | 3<<8, 0x7ff, 1, 0xffe},
+		{"tap 11", 0xf<<10 | 3<<8, 0x0800, 1, 0x1000},
+		{"tap 12", 0xf<<10 | 3<<8, 0x1000, 1, 0x2000},
+		{"tap 15 shifts out", 0xf<<10 | 3<<8, 0x8000, 1, 0},
+		{"other bits don't feed back", 0xf<<10 | 3<<8, 0x4201, 1, 0x8403},
+		{"slowest clocks on the first sample", 0, 0, 1, 1},
+		{"slowest waits 0x20000/4 samples", 0, 0, 32768, 1},
+		{"slowest clocks again", 0, 0, 32769, 3},
+	} {
+		s := New(nil)
+		s.control = c.control
+		s.noiseLevel = c.level
+		for i := 0; i < c.ticks; i++ {
+			s.tickNoise()
+		}
+		if s.noiseLevel != c.want {
+			t.Errorf("%s: 0x%04x from 0x%04x after %d ticks, want 0x%04x", c.name, s.noiseLevel, c.level, c.ticks, c.want)
+		}
+	}
+}
+
+func TestReverbAddr(t *testing.T) {
+	const base = 0x7ff80 //mBASE 0xfff0 leaves a 0x80 byte work area
+	for _, c := range []struct {
+		name   string
+		pos    uint32
+		offset int32
+		want   uint32
+	}{
+		{"start", base, 0, base},
+		{"end wraps to the start", base, 0x80, base},
+		{"past the end", base, 0x82, base + 2},
+		{"before the start wraps to the end", base, -2, 0x7fffe},
+		{"odd offsets round down", base, 3, base + 2},
+		{"from the buffer address", base + 0x7e, 4, base + 2},
+	} {
+		s := New(nil)
+		s.regs[0x1a2>>1] = 0xfff0
+		s.reverb.pos = c.pos
+		if got := s.reverbAddr(c.offset); got != c.want {
+			t.Errorf("%s: 0x%05x, want 0x%05x", c.name, got, c.want)
+		}
+	}
+}
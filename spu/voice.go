@@ -0,0 +1,120 @@
+package spu
+
+const (
+	BLOCK_SIZE    = 16 //Bytes per ADPCM block
+	BLOCK_SAMPLES = 28
+	HISTORY       = 3 //Samples of the previous block kept for interpolation
+)
+
+// ADPCM block flags, byte 1 of the block
+const (
+	LOOP_END    uint8 = 0x01
+	LOOP_REPEAT uint8 = 0x02
+	LOOP_START  uint8 = 0x04
+)
+
+// Prediction filters, weights of the previous two samples in 1/64
+var ADPCM_POS = [5]int32{0, 60, 115, 98, 122}
+var ADPCM_NEG = [5]int32{0, 0, -52, -55, -60}
+
+type Voice struct {
+	left         Volume
+	right        Volume
+	pitch        uint16
+	start        uint16 //Addresses are in 8 byte units
+	repeat       uint16
+	current      uint16 //Block being played
+	adsr         ADSR
+	counter      uint32                         //Pitch counter, sample index from bit 12
+	samples      [HISTORY + BLOCK_SAMPLES]int16 //Oldest first
+	flags        uint8                          //Loop flags of the current block
+	decoded      bool
+	ignoreRepeat bool  //Repeat address written while playing, loop start flags leave it alone
+	output       int16 //Last sample after ADSR
+}
+
+func (v *Voice) KeyOn() {
+	v.current = v.start &^ 1 //Blocks are 16 byte aligned
+	v.counter = 0
+	v.samples = [HISTORY + BLOCK_SAMPLES]int16{}
+	v.decoded = false
+	v.ignoreRepeat = false
+	v.adsr.level = 0
+	v.adsr.SetPhase(Attack)
+}
+
+func (v *Voice) KeyOff() {
+	if v.adsr.phase != Off {
+		v.adsr.SetPhase(Release)
+	}
+}
+
+func (v *Voice) On() bool {
+	return v.adsr.phase != Off
+}
+
+func (v *Voice) SetRepeat(val uint16) {
+	v.repeat = val
+	v.ignoreRepeat = v.ignoreRepeat || v.On()
+}
+
+func (v *Voice) decode(ram []uint8) { //Next 28 samples, the last two of the previous block seed the filter
+	block := ram[uint32(v.current)*8:]
+
+	shift := block[0] & 0xf
+	if shift > 12 { //13-15 act like 9
+		shift = 9
+	}
+	filter := min(block[0]>>4&7, 4)
+	v.flags = block[1]
+	if v.flags&LOOP_START != 0 && !v.ignoreRepeat {
+		v.repeat = v.current
+	}
+
+	copy(v.samples[:HISTORY], v.samples[BLOCK_SAMPLES:])
+	old, older := int32(v.samples[HISTORY-1]), int32(v.samples[HISTORY-2])
+
+	for i := 0; i < BLOCK_SAMPLES; i++ {
+		nibble := uint16(block[2+i/2]>>(4*(i&1))) & 0xf
+		s := int32(int16(nibble<<12)) >> shift
+		s += (old*ADPCM_POS[filter] + older*ADPCM_NEG[filter] + 32) >> 6
+		s = min(max(s, -0x8000), 0x7fff)
+
+		v.samples[HISTORY+i] = int16(s)
+		older, old = old, s
+	}
+	v.decoded = true
+}
+
+func (v *Voice) interpolate() int16 { //Gaussian interpolation over the four newest samples
+	i := v.counter >> 4 & 0xff
+	s := v.counter>>12 + HISTORY
+
+	out := int32(GAUSS_TABLE[0x0ff-i]) * int32(v.samples[s-3]) >> 15
+	out += int32(GAUSS_TABLE[0x1ff-i]) * int32(v.samples[s-2]) >> 15
+	out += int32(GAUSS_TABLE[0x100+i]) * int32(v.samples[s-1]) >> 15
+	out += int32(GAUSS_TABLE[0x000+i]) * int32(v.samples[s]) >> 15
+	return int16(out)
+}
+
+// Moves the pitch counter by step, returns true when the block ended with the loop end flag
+func (v *Voice) advance(step uint32) bool {
+	v.counter += min(step, 0x4000)
+	if v.counter>>12 < BLOCK_SAMPLES {
+		return false
+	}
+	v.counter -= BLOCK_SAMPLES << 12
+	v.decoded = false
+
+	if v.flags&LOOP_END == 0 {
+		v.current += BLOCK_SIZE / 8
+		return false
+	}
+
+	v.current = v.repeat &^ 1
+	if v.flags&LOOP_REPEAT == 0 { //One shot, the voice fades out at once
+		v.adsr.SetPhase(Release)
+		v.adsr.level = 0
+	}
+	return true
+}
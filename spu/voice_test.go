@@ -0,0 +1,138 @@
+package spu
+
+import (
+	"testing"
+)
+
+// Sound RAM holding the blocks in order from address 0
+func blocks(bs ...[BLOCK_SIZE]uint8) []uint8 {
+	ram := make([]uint8, RAM_SIZE)
+	for i, b := range bs {
+		copy(ram[i*BLOCK_SIZE:], b[:])
+	}
+	return ram
+}
+
+func TestDecode(t *testing.T) {
+	first := [BLOCK_SIZE]uint8{0x00, 0, 0x21, 0xf8} //Shift 0, filter 0
+	first[15] = 0x40                                //Last sample 0x4000
+
+	for _, c := range []struct {
+		name   string
+		header uint8
+		data   uint8 //Every byte of the second block
+		want   []int16
+	}{
+		{"filter 0 shift 0", 0x00, 0x21, []int16{0x1000, 0x2000, 0x1000}},
+		{"shift 4, negative nibble", 0x04, 0x0f, []int16{-0x100, 0, -0x100}},
+		{"shifts 13-15 act like 9", 0x0d, 0x11, []int16{8, 8, 8}},
+		{"filter 1 decays", 0x10, 0x00, []int16{15360, 14400, 13500}},
+		{"filter 2 clamps", 0x20, 0x00, []int16{29440, 0x7fff, 0x7fff}},
+	} {
+		second := [BLOCK_SIZE]uint8{c.header}
+		for i := 2; i < BLOCK_SIZE; i++ {
+			second[i] = c.data
+		}
+		ram := blocks(first, second)
+
+		var v Voice
+		v.decode(ram)
+		if got := v.samples[HISTORY : HISTORY+4]; got[0] != 0x1000 || got[1] != 0x2000 || got[2] != -0x8000 || got[3] != -0x1000 {
+			t.Fatalf("first block starts %v", got)
+		}
+
+		v.current = BLOCK_SIZE / 8
+		v.decode(ram)
+		if got := v.samples[HISTORY-1]; got != 0x4000 {
+			t.Errorf("%s: history ends 0x%04x, want the previous block's 0x4000", c.name, got)
+		}
+		for i, want := range c.want {
+			if got := v.samples[HISTORY+i]; got != want {
+				t.Errorf("%s: sample %d = %d, want %d", c.name, i, got, want)
+			}
+		}
+	}
+}
+
+// Decodes and plays the current block, 4 samples at a time like the fastest pitch
+func playBlock(v *Voice, ram []uint8) bool {
+	v.decode(ram)
+	ended := false
+	for i := 0; i < BLOCK_SAMPLES/4; i++ {
+		ended = v.advance(0x4000)
+	}
+	return ended
+}
+
+func TestLoopFlags(t *testing.T) {
+	ram := blocks(
+		[BLOCK_SIZE]uint8{0, 0},
+		[BLOCK_SIZE]uint8{0, LOOP_START},
+		[BLOCK_SIZE]uint8{0, 0},
+		[BLOCK_SIZE]uint8{0, LOOP_END | LOOP_REPEAT},
+		[BLOCK_SIZE]uint8{0, LOOP_END},
+	)
+
+	v := Voice{}
+	v.KeyOn()
+	for i, want := range []uint16{2, 4, 6, 2, 4} { //Block 1 sets the repeat address, block 3 jumps back to it
+		if ended := playBlock(&v, ram); ended != (i == 3) {
+			t.Errorf("block %d: ended = %v", i, ended)
+		}
+		if v.current != want {
+			t.Errorf("after block %d: at 0x%x, want 0x%x", i, v.current, want)
+		}
+	}
+	if v.adsr.phase != Attack {
+		t.Errorf("looping voice in phase %d", v.adsr.phase)
+	}
+
+	v.start = 8 //The lone LOOP_END block
+	v.KeyOn()
+	v.SetRepeat(0)
+	v.adsr.level = 0x1000
+	if !playBlock(&v, ram) || v.adsr.phase != Release || v.adsr.level != 0 {
+		t.Errorf("one shot end: phase %d, level 0x%04x", v.adsr.phase, v.adsr.level)
+	}
+}
+
+func TestRepeatWrittenWhilePlaying(t *testing.T) {
+	ram := blocks(
+		[BLOCK_SIZE]uint8{0, LOOP_START},
+		[BLOCK_SIZE]uint8{0, LOOP_END | LOOP_REPEAT},
+	)
+
+	v := Voice{}
+	v.KeyOn()
+	v.SetRepeat(0x100) //Loop start flags no longer move it
+	playBlock(&v, ram)
+	playBlock(&v, ram)
+	if v.current != 0x100 {
+		t.Errorf("looped to 0x%x, want 0x100", v.current)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		counter uint32
+		index   int //Sample set to 0x4000
+		want    int16
+	}{
+		{"oldest", 0, 0, 0x12c7 / 2},
+		{"second", 0, 1, 0x59b3 / 2},
+		{"third", 0, 2, 0x1307 / 2},
+		{"newest", 0, 3, -1},
+		{"halfway, oldest", 0x800, 0, 0x19c / 2},
+		{"halfway, newest", 0x800, 3, 0x1a8 / 2},
+		{"window moves with the counter", 0x1000, 2, 0x59b3 / 2},
+		{"outside the window", 0x1000, 0, 0},
+	} {
+		var v Voice
+		v.counter = c.counter
+		v.samples[c.index] = 0x4000
+		if got := v.interpolate(); got != c.want {
+			t.Errorf("%s: %d, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package spu
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+const WAV_HEADER_SIZE = 44
+
+// 16 bit stereo PCM file, the sizes in the header are filled in on Close
+type WAVSink struct {
+	w     io.WriteSeeker
+	out   *bufio.Writer
+	bytes uint32 //Sample data written so far
+	err   error
+}
+
+func NewWAVSink(w io.WriteSeeker) (*WAVSink, error) {
+	sink := &WAVSink{w: w, out: bufio.NewWriter(w)}
+	if err := sink.header(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+func (s *WAVSink) header() error {
+	h := make([]byte, 0, WAV_HEADER_SIZE)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, WAV_HEADER_SIZE-8+s.bytes)
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, 1) //PCM
+	h = binary.LittleEndian.AppendUint16(h, 2)
+	h = binary.LittleEndian.AppendUint32(h, SAMPLE_RATE)
+	h = binary.LittleEndian.AppendUint32(h, SAMPLE_RATE*4) //Bytes per second
+	h = binary.LittleEndian.AppendUint16(h, 4)             //Bytes per frame
+	h = binary.LittleEndian.AppendUint16(h, 16)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, s.bytes)
+
+	_, err := s.out.Write(h)
+	return err
+}
+
+func (s *WAVSink) Push(samples []int16) {
+	if s.err != nil {
+		return
+	}
+	s.err = binary.Write(s.out, binary.LittleEndian, samples)
+	s.bytes += uint32(len(samples) * 2)
+}
+
+func (s *WAVSink) Close() error { //Rewrites the header with the final sizes, the caller closes the file
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.out.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := s.header(); err != nil {
+		return err
+	}
+	if err := s.out.Flush(); err != nil {
+		return err
+	}
+	_, err := s.w.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,259 @@
+package timers
+
+import (
+	"github.com/Koops0/GPSXE/irq"
+)
+
+// The three root counters at 0x1f801100
+type Timers struct {
+	timers [3]Timer
+	irq    *irq.InterruptState
+}
+
+type Timer struct {
+	instance    uint32 //0, 1 or 2
+	counter     uint16
+	target      uint16
+	syncEnable  bool
+	syncMode    SyncMode
+	targetReset bool //Wrap at target instead of 0xffff
+	targetIrq   bool
+	maxIrq      bool
+	repeatIrq   bool
+	toggleIrq   bool
+	clockSource ClockSource
+	source      uint32 //Raw mode bits 8-9
+	irqBit      bool   //Mode bit 10, 0 means IRQ requested
+	targetFlag  bool   //Mode bit 11, cleared on read
+	maxFlag     bool   //Mode bit 12, cleared on read
+	fired       bool   //One-shot IRQ already sent
+	inBlank     bool   //HBlank for timer 0, VBlank for timer 1
+	released    bool   //Sync mode 3 saw its blank and free runs
+	prescale    uint32
+}
+
+type SyncMode uint8
+
+type ClockSource uint8
+
+const (
+	SysClock ClockSource = iota
+	DotClock
+	HBlank
+	SysClockDiv8
+)
+
+func New(irq *irq.InterruptState) *Timers {
+	t := &Timers{irq: irq}
+	for n := range t.timers {
+		t.timers[n] = Timer{instance: uint32(n), irqBit: true}
+	}
+	return t
+}
+
+func (t *Timers) Load(offset uint32) uint32 {
+	timer := &t.timers[offset>>4]
+
+	switch offset & 0xf {
+	case 0:
+		return uint32(timer.counter)
+	case 4:
+		return timer.Mode()
+	case 8:
+		return uint32(timer.target)
+	default:
+		return 0
+	}
+}
+
+func (t *Timers) Store(offset uint32, val uint32) {
+	timer := &t.timers[offset>>4]
+
+	switch offset & 0xf {
+	case 0:
+		timer.counter = uint16(val)
+	case 4:
+		timer.SetMode(val)
+	case 8:
+		timer.target = uint16(val)
+	}
+}
+
+func (t *Timers) Tick(cycles uint32) { //System clock
+	for n := range t.timers {
+		timer := &t.timers[n]
+
+		switch timer.clockSource {
+		case SysClock:
+			t.count(timer, cycles)
+		case SysClockDiv8:
+			timer.prescale += cycles
+			t.count(timer, timer.prescale/8)
+			timer.prescale %= 8
+		}
+	}
+}
+
+func (t *Timers) Dotclock(dots uint32) { //GPU dot clock, timer 0 only
+	if t.timers[0].clockSource == DotClock {
+		t.count(&t.timers[0], dots)
+	}
+}
+
+func (t *Timers) HBlank(count uint32, active bool) { //count is the number of HBlanks started
+	if t.timers[1].clockSource == HBlank {
+		t.count(&t.timers[1], count)
+	}
+	t.blank(&t.timers[0], count, active)
+}
+
+func (t *Timers) VBlank(count uint32, active bool) {
+	t.blank(&t.timers[1], count, active)
+}
+
+func (t *Timers) blank(timer *Timer, starts uint32, active bool) { //Sync modes of timers 0 and 1
+	timer.inBlank = active
+
+	if !timer.syncEnable || starts == 0 {
+		return
+	}
+
+	switch timer.syncMode {
+	case 1, 2:
+		timer.counter = 0
+	case 3:
+		timer.released = true //Free run from now on
+	}
+}
+
+func (timer *Timer) paused() bool {
+	if !timer.syncEnable || timer.released {
+		return false
+	}
+
+	if timer.instance == 2 {
+		return timer.syncMode == 0 || timer.syncMode == 3
+	}
+
+	switch timer.syncMode {
+	case 0:
+		return timer.inBlank
+	case 2:
+		return !timer.inBlank
+	case 3:
+		return true //Until the first blank
+	default:
+		return false
+	}
+}
+
+func (t *Timers) count(timer *Timer, ticks uint32) {
+	if timer.paused() {
+		return
+	}
+
+	for ; ticks > 0; ticks-- {
+		if timer.targetReset && timer.counter == timer.target {
+			timer.counter = 0
+		} else {
+			timer.counter++
+		}
+
+		if timer.counter == timer.target {
+			timer.targetFlag = true
+			if timer.targetIrq {
+				t.interrupt(timer)
+			}
+		}
+
+		if timer.counter == 0xffff {
+			timer.maxFlag = true
+			if timer.maxIrq {
+				t.interrupt(timer)
+			}
+		}
+	}
+}
+
+func (t *Timers) interrupt(timer *Timer) {
+	if !timer.repeatIrq && timer.fired {
+		return
+	}
+	timer.fired = true
+
+	if timer.toggleIrq {
+		timer.irqBit = !timer.irqBit
+		if timer.irqBit {
+			return
+		}
+	}
+
+	t.irq.Assert(irq.Timer0 + irq.Interrupt(timer.instance))
+}
+
+func (timer *Timer) Mode() uint32 { //Reading clears the reached flags
+	r := uint32(0)
+
+	r |= boolToUint32(timer.syncEnable) << 0
+	r |= uint32(timer.syncMode) << 1
+	r |= boolToUint32(timer.targetReset) << 3
+	r |= boolToUint32(timer.targetIrq) << 4
+	r |= boolToUint32(timer.maxIrq) << 5
+	r |= boolToUint32(timer.repeatIrq) << 6
+	r |= boolToUint32(timer.toggleIrq) << 7
+	r |= timer.source << 8
+	r |= boolToUint32(timer.irqBit) << 10
+	r |= boolToUint32(timer.targetFlag) << 11
+	r |= boolToUint32(timer.maxFlag) << 12
+
+	timer.targetFlag = false
+	timer.maxFlag = false
+
+	return r
+}
+
+func (timer *Timer) SetMode(val uint32) { //Writing the mode also resets the counter
+	timer.syncEnable = val&1 != 0
+	timer.syncMode = SyncMode((val >> 1) & 3)
+	timer.targetReset = (val>>3)&1 != 0
+	timer.targetIrq = (val>>4)&1 != 0
+	timer.maxIrq = (val>>5)&1 != 0
+	timer.repeatIrq = (val>>6)&1 != 0
+	timer.toggleIrq = (val>>7)&1 != 0
+
+	source := (val >> 8) & 3
+	switch timer.instance {
+	case 0:
+		if source&1 != 0 {
+			timer.clockSource = DotClock
+		} else {
+			timer.clockSource = SysClock
+		}
+	case 1:
+		if source&1 != 0 {
+			timer.clockSource = HBlank
+		} else {
+			timer.clockSource = SysClock
+		}
+	case 2:
+		if source&2 != 0 {
+			timer.clockSource = SysClockDiv8
+		} else {
+			timer.clockSource = SysClock
+		}
+	}
+
+	timer.counter = 0
+	timer.irqBit = true
+	timer.fired = false
+	timer.released = false
+	timer.prescale = 0
+	timer.source = source
+}
+
+func boolToUint32(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,214 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Koops0/GPSXE/disasm"
+)
+
+// Lines listing at least this many registers are full register dumps
+const FULL_DUMP_REGS = 31
+
+// Turns a reference emulator's text log into a binary trace, one executed instruction per line.
+// The PC is a "pc=" field or the first bare 8 digit hex number, an 8 digit hex number right
+// after it is the opcode. Registers appear as name=value or name:value, with ABI names, rN or $N.
+// Full register dumps are diffed line to line, regsBefore says whether a dump shows the state
+// before its instruction ran. The first dump only seeds the state, with nothing before it to
+// diff against. Shorter lists are taken as that instruction's writes.
+// Lines without a PC are skipped. Returns the number of records written.
+func Convert(in io.Reader, out io.Writer, regsBefore bool) (int, error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var w *Writer
+	var state [32]uint32
+	seeded := false     //A full dump has set state
+	var pending *Record //Dumped before execution, waiting for the next line's state
+	count := 0
+
+	emit := func(r *Record) error {
+		count++
+		return w.Write(r)
+	}
+
+	for scanner.Scan() {
+		p := parseLine(scanner.Text())
+		if !p.hasPC {
+			continue
+		}
+		rec := &p.rec
+
+		if w == nil {
+			flags := uint32(0)
+			if p.hasOpcode {
+				flags |= HAS_OPCODE
+			}
+			var err error
+			if w, err = NewWriter(out, flags); err != nil {
+				return count, err
+			}
+		}
+
+		if p.count < FULL_DUMP_REGS {
+			for i := range p.regs {
+				if p.regs[i] != nil {
+					rec.Regs = append(rec.Regs, RegWrite{Index: uint8(i), Value: *p.regs[i]})
+					state[i] = *p.regs[i]
+				}
+			}
+			if pending != nil {
+				if err := emit(pending); err != nil {
+					return count, err
+				}
+				pending = nil
+			}
+			if err := emit(rec); err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		changes := diffState(&state, p.regs)
+		if !seeded {
+			changes = nil
+			seeded = true
+		}
+		if regsBefore {
+			if pending != nil {
+				pending.Regs = changes
+				if err := emit(pending); err != nil {
+					return count, err
+				}
+			}
+			pending = rec
+		} else {
+			rec.Regs = changes
+			if err := emit(rec); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	if w == nil {
+		return 0, fmt.Errorf("no trace lines found")
+	}
+	if pending != nil { //Nothing after it to tell what it wrote
+		if err := emit(pending); err != nil {
+			return count, err
+		}
+	}
+	return count, w.Flush()
+}
+
+func diffState(state *[32]uint32, regs [32]*uint32) []RegWrite {
+	var changes []RegWrite
+	for i := 1; i < 32; i++ {
+		if regs[i] != nil && *regs[i] != state[i] {
+			changes = append(changes, RegWrite{Index: uint8(i), Value: *regs[i]})
+			state[i] = *regs[i]
+		}
+	}
+	return changes
+}
+
+type parsed struct {
+	rec       Record
+	regs      [32]*uint32
+	count     int //Registers on the line
+	hasPC     bool
+	hasOpcode bool
+}
+
+func parseLine(line string) parsed {
+	var p parsed
+	afterPC := false //The previous token was a bare PC
+
+	tokens := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '|' || r == '(' || r == ')' || r == '[' || r == ']'
+	})
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		name, value, isPair := strings.Cut(tok, "=")
+		if !isPair {
+			name, value, isPair = strings.Cut(tok, ":")
+		}
+
+		reg, isReg := regIndex(name)
+		isPC := strings.EqualFold(name, "pc")
+		if isPair && (isReg || isPC) {
+			if value == "" && i+1 < len(tokens) { //"name: value"
+				i++
+				value = tokens[i]
+			}
+			if v, ok := parseHex32(value); ok {
+				if isPC {
+					p.rec.PC = v
+					p.hasPC = true
+				} else {
+					if p.regs[reg] == nil {
+						p.count++
+					}
+					p.regs[reg] = &v
+				}
+			}
+			afterPC = isPC //An opcode may follow "pc=" too
+			continue
+		}
+
+		bare := strings.TrimSuffix(tok, ":")
+		if len(strings.TrimPrefix(bare, "0x")) == 8 {
+			if v, ok := parseHex32(bare); ok {
+				if !p.hasPC {
+					p.rec.PC = v
+					p.hasPC = true
+					afterPC = true
+					continue
+				}
+				if afterPC {
+					p.rec.Opcode = v
+					p.hasOpcode = true
+				}
+			}
+		}
+		afterPC = false
+	}
+
+	return p
+}
+
+func parseHex32(s string) (uint32, bool) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 32)
+	return uint32(v), err == nil
+}
+
+func regIndex(name string) (int, bool) { //ABI name, rN or $N
+	if strings.HasPrefix(name, "$") {
+		if i, err := strconv.Atoi(name[1:]); err == nil && i >= 0 && i < 32 {
+			return i, true
+		}
+	}
+	name = strings.ToLower(strings.TrimPrefix(name, "$"))
+	for i, r := range disasm.REG_NAMES {
+		if name == r {
+			return i, true
+		}
+	}
+	if name == "s8" {
+		return 30, true
+	}
+
+	if len(name) > 1 && name[0] == 'r' {
+		if i, err := strconv.Atoi(name[1:]); err == nil && i >= 0 && i < 32 {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+package trace
+
+// What the recorder needs to see of the CPU
+type Target interface {
+	PC() uint32
+	Reg(index uint32) uint32
+	Peek32(addr uint32) (uint32, bool)
+}
+
+// Records every step of the CPU, memory accesses arrive as a biosmap.Watcher
+type Recorder struct {
+	w      *Writer
+	rec    Record
+	before [32]uint32
+	drop   bool  //The step never executed
+	err    error //First write error, recording stops there
+}
+
+func NewRecorder(w *Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+func (r *Recorder) Begin(t Target) { //Before the instruction
+	r.rec.PC = t.PC()
+	r.rec.Opcode, _ = t.Peek32(r.rec.PC)
+	r.rec.Regs = r.rec.Regs[:0]
+	r.rec.Mem = r.rec.Mem[:0]
+	r.drop = false
+
+	for i := range r.before {
+		r.before[i] = t.Reg(uint32(i))
+	}
+}
+
+func (r *Recorder) End(t Target) { //After it, registers are diffed against Begin
+	if r.err != nil || r.drop {
+		return
+	}
+
+	for i := range r.before {
+		if v := t.Reg(uint32(i)); v != r.before[i] {
+			r.rec.Regs = append(r.rec.Regs, RegWrite{Index: uint8(i), Value: v})
+		}
+	}
+	r.err = r.w.Write(&r.rec)
+}
+
+func (r *Recorder) Drop() { //An interrupt was taken instead of the instruction Begin saw
+	r.drop = true
+}
+
+func (r *Recorder) Load(addr uint32, size uint32, val uint32) { //biosmap.Watcher
+	r.rec.Mem = append(r.rec.Mem, Access{Addr: addr, Value: val, Size: uint8(size)})
+}
+
+func (r *Recorder) Store(addr uint32, size uint32, val uint32) { //biosmap.Watcher
+	r.rec.Mem = append(r.rec.Mem, Access{Addr: addr, Value: val, Size: uint8(size), Write: true})
+}
+
+// Flushes the trace, reporting the first error seen while recording
+func (r *Recorder) Close() error {
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
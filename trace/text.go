@@ -0,0 +1,85 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Koops0/GPSXE/disasm"
+)
+
+// One line per step: pc, opcode and disassembly, then register writes and memory accesses
+func (r *Record) Format(flags uint32) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%08x", r.PC)
+	if flags&HAS_OPCODE != 0 {
+		fmt.Fprintf(&b, " %08x  %-32s", r.Opcode, disasm.Disassemble(r.PC, r.Opcode))
+	}
+
+	for _, reg := range r.Regs {
+		fmt.Fprintf(&b, " %s=%08x", disasm.REG_NAMES[reg.Index], reg.Value)
+	}
+
+	for _, a := range r.Mem {
+		kind := 'r'
+		if a.Write {
+			kind = 'w'
+		}
+		fmt.Fprintf(&b, " %c%d[%08x]=%0*x", kind, a.Size*8, a.Addr, a.Size*2, a.Value)
+	}
+
+	return strings.TrimRight(b.String(), " ")
+}
+
+// Why two records for the same step disagree, "" if they don't.
+// Only fields present in both traces are compared.
+func Compare(a *Record, b *Record, flags uint32) string {
+	if a.PC != b.PC {
+		return fmt.Sprintf("pc 0x%08x != 0x%08x", a.PC, b.PC)
+	}
+
+	if flags&HAS_OPCODE != 0 && a.Opcode != b.Opcode {
+		return fmt.Sprintf("opcode 0x%08x != 0x%08x", a.Opcode, b.Opcode)
+	}
+
+	if diff := compareRegs(a.Regs, b.Regs); diff != "" {
+		return diff
+	}
+
+	if flags&HAS_MEMORY != 0 {
+		if len(a.Mem) != len(b.Mem) {
+			return fmt.Sprintf("%d memory accesses != %d", len(a.Mem), len(b.Mem))
+		}
+		for i := range a.Mem {
+			if a.Mem[i] != b.Mem[i] {
+				return fmt.Sprintf("memory access %d differs", i)
+			}
+		}
+	}
+
+	return ""
+}
+
+func compareRegs(a []RegWrite, b []RegWrite) string {
+	var va, vb [32]*uint32
+	for i := range a {
+		va[a[i].Index&0x1f] = &a[i].Value
+	}
+	for i := range b {
+		vb[b[i].Index&0x1f] = &b[i].Value
+	}
+
+	for i := range va {
+		name := disasm.REG_NAMES[i]
+		switch {
+		case va[i] == nil && vb[i] == nil:
+		case va[i] == nil:
+			return fmt.Sprintf("%s unchanged != %08x", name, *vb[i])
+		case vb[i] == nil:
+			return fmt.Sprintf("%s %08x != unchanged", name, *va[i])
+		case *va[i] != *vb[i]:
+			return fmt.Sprintf("%s %08x != %08x", name, *va[i], *vb[i])
+		}
+	}
+	return ""
+}
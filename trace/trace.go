@@ -0,0 +1,192 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Binary trace: the magic, a flags word, then one record per executed step.
+// Everything is little endian.
+const MAGIC = "GPSXTRC1"
+
+// Header flags, converted reference traces may lack some of the fields
+const (
+	HAS_OPCODE uint32 = 1 << 0
+	HAS_MEMORY uint32 = 1 << 1
+)
+
+type RegWrite struct {
+	Index uint8
+	Value uint32
+}
+
+type Access struct {
+	Addr  uint32
+	Value uint32
+	Size  uint8 //Bytes
+	Write bool
+}
+
+// One step of CPU.Run_next
+type Record struct {
+	PC     uint32
+	Opcode uint32
+	Regs   []RegWrite //GPRs that changed
+	Mem    []Access   //Data accesses, in program order
+}
+
+var ErrNotTrace = errors.New("not a trace file")
+
+type Writer struct {
+	out   *bufio.Writer
+	flags uint32
+	buf   []uint8
+}
+
+func NewWriter(out io.Writer, flags uint32) (*Writer, error) {
+	w := &Writer{out: bufio.NewWriter(out), flags: flags}
+
+	header := make([]uint8, len(MAGIC)+4)
+	copy(header, MAGIC)
+	binary.LittleEndian.PutUint32(header[len(MAGIC):], flags)
+	if _, err := w.out.Write(header); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Flags() uint32 {
+	return w.flags
+}
+
+func (w *Writer) Write(r *Record) error {
+	if len(r.Regs) > 0xff || len(r.Mem) > 0xffff {
+		return fmt.Errorf("record at 0x%08x is too large", r.PC)
+	}
+
+	b := w.buf[:0]
+	b = binary.LittleEndian.AppendUint32(b, r.PC)
+	if w.flags&HAS_OPCODE != 0 {
+		b = binary.LittleEndian.AppendUint32(b, r.Opcode)
+	}
+
+	b = append(b, uint8(len(r.Regs)))
+	for _, reg := range r.Regs {
+		b = append(b, reg.Index)
+		b = binary.LittleEndian.AppendUint32(b, reg.Value)
+	}
+
+	if w.flags&HAS_MEMORY != 0 {
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(r.Mem)))
+		for _, a := range r.Mem {
+			kind := a.Size
+			if a.Write {
+				kind |= 0x80
+			}
+			b = append(b, kind)
+			b = binary.LittleEndian.AppendUint32(b, a.Addr)
+			b = binary.LittleEndian.AppendUint32(b, a.Value)
+		}
+	}
+
+	w.buf = b
+	_, err := w.out.Write(b)
+	return err
+}
+
+func (w *Writer) Flush() error {
+	return w.out.Flush()
+}
+
+type Reader struct {
+	in    *bufio.Reader
+	flags uint32
+}
+
+func NewReader(in io.Reader) (*Reader, error) {
+	r := &Reader{in: bufio.NewReader(in)}
+
+	header := make([]uint8, len(MAGIC)+4)
+	if _, err := io.ReadFull(r.in, header); err != nil || string(header[:len(MAGIC)]) != MAGIC {
+		return nil, ErrNotTrace
+	}
+	r.flags = binary.LittleEndian.Uint32(header[len(MAGIC):])
+	return r, nil
+}
+
+func (r *Reader) Flags() uint32 {
+	return r.flags
+}
+
+// The next record, io.EOF once the trace is exhausted
+func (r *Reader) Next() (*Record, error) {
+	rec := &Record{}
+
+	pc, err := r.u32()
+	if err != nil {
+		return nil, err //A clean io.EOF between records
+	}
+	rec.PC = pc
+
+	if r.flags&HAS_OPCODE != 0 {
+		if rec.Opcode, err = r.u32(); err != nil {
+			return nil, truncated(err)
+		}
+	}
+
+	n, err := r.in.ReadByte()
+	if err != nil {
+		return nil, truncated(err)
+	}
+	rec.Regs = make([]RegWrite, n)
+	for i := range rec.Regs {
+		if rec.Regs[i].Index, err = r.in.ReadByte(); err != nil {
+			return nil, truncated(err)
+		}
+		if rec.Regs[i].Value, err = r.u32(); err != nil {
+			return nil, truncated(err)
+		}
+	}
+
+	if r.flags&HAS_MEMORY != 0 {
+		var count [2]uint8
+		if _, err := io.ReadFull(r.in, count[:]); err != nil {
+			return nil, truncated(err)
+		}
+		rec.Mem = make([]Access, binary.LittleEndian.Uint16(count[:]))
+		for i := range rec.Mem {
+			kind, err := r.in.ReadByte()
+			if err != nil {
+				return nil, truncated(err)
+			}
+			rec.Mem[i].Size = kind & 0x7f
+			rec.Mem[i].Write = kind&0x80 != 0
+			if rec.Mem[i].Addr, err = r.u32(); err != nil {
+				return nil, truncated(err)
+			}
+			if rec.Mem[i].Value, err = r.u32(); err != nil {
+				return nil, truncated(err)
+			}
+		}
+	}
+
+	return rec, nil
+}
+
+func (r *Reader) u32() (uint32, error) {
+	var b [4]uint8
+	if _, err := io.ReadFull(r.in, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
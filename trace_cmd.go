@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Koops0/GPSXE/trace"
+)
+
+// gpsxe tracedump <file.trace>
+func Tracedump_command(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gpsxe tracedump <file.trace>")
+		return 2
+	}
+
+	r, f, err := openTrace(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return 0
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintln(out, rec.Format(r.Flags()))
+	}
+}
+
+// gpsxe tracediff [-context n] <a.trace> <b.trace>, exits 1 at the first divergence
+func Tracediff_command(args []string) int {
+	fs := flag.NewFlagSet("tracediff", flag.ContinueOnError)
+	context := fs.Int("context", 8, "Matching steps to show before the divergence")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gpsxe tracediff [flags] <a.trace> <b.trace>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	a, fa, err := openTrace(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer fa.Close()
+	b, fb, err := openTrace(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer fb.Close()
+
+	flags := a.Flags() & b.Flags() //Compare what both traces have
+	var history []*trace.Record
+
+	for step := uint64(0); ; step++ {
+		ra, erra := a.Next()
+		rb, errb := b.Next()
+
+		if erra == io.EOF && errb == io.EOF {
+			fmt.Printf("Traces match, %d steps\n", step)
+			return 0
+		}
+		if erra != nil && erra != io.EOF {
+			fmt.Fprintln(os.Stderr, fs.Arg(0)+":", erra)
+			return 2
+		}
+		if errb != nil && errb != io.EOF {
+			fmt.Fprintln(os.Stderr, fs.Arg(1)+":", errb)
+			return 2
+		}
+
+		reason := ""
+		switch {
+		case erra == io.EOF:
+			reason = fs.Arg(0) + " ends first"
+		case errb == io.EOF:
+			reason = fs.Arg(1) + " ends first"
+		default:
+			reason = trace.Compare(ra, rb, flags)
+		}
+
+		if reason == "" {
+			history = append(history, ra)
+			if len(history) > *context {
+				history = history[1:]
+			}
+			continue
+		}
+
+		fmt.Printf("Divergence at step %d: %s\n", step, reason)
+		for i, rec := range history {
+			fmt.Printf("  %d  %s\n", step-uint64(len(history)-i), rec.Format(flags))
+		}
+		if ra != nil {
+			fmt.Printf("< %d  %s\n", step, ra.Format(flags))
+		}
+		if rb != nil {
+			fmt.Printf("> %d  %s\n", step, rb.Format(flags))
+		}
+		return 1
+	}
+}
+
+// gpsxe traceconv [-regs before|after] <reference.log> <out.trace>
+func Traceconv_command(args []string) int {
+	fs := flag.NewFlagSet("traceconv", flag.ContinueOnError)
+	regs := fs.String("regs", "before", "Whether full register dumps show the state before or after their instruction")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gpsxe traceconv [flags] <reference.log> <out.trace>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || (*regs != "before" && *regs != "after") {
+		fs.Usage()
+		return 2
+	}
+
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer in.Close()
+
+	out, err := os.Create(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer out.Close()
+
+	n, err := trace.Convert(in, out, *regs == "before")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error converting trace:", err)
+		return 1
+	}
+	fmt.Printf("Converted %d steps\n", n)
+	return 0
+}
+
+func openTrace(path string) (*trace.Reader, *os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := trace.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, f, nil
+}
@@ -0,0 +1,51 @@
+package tty
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Console output from BIOS putchar and the EX2 DUART, one line at a time
+type TTY struct {
+	out       *bufio.Writer
+	line      []uint8
+	lineStart uint64 //Cycle of the line's first character
+	clock     func() uint64
+}
+
+func New(out io.Writer, clock func() uint64) *TTY {
+	return &TTY{
+		out:   bufio.NewWriter(out),
+		line:  make([]uint8, 0, 256),
+		clock: clock,
+	}
+}
+
+func (t *TTY) Putchar(c uint8) {
+	if len(t.line) == 0 {
+		t.lineStart = t.clock()
+	}
+
+	switch c {
+	case '\n':
+		t.emit()
+	case '\r', 0:
+		//Dropped, lines end on \n
+	default:
+		t.line = append(t.line, c)
+	}
+}
+
+func (t *TTY) emit() {
+	fmt.Fprintf(t.out, "[%12d] %s\n", t.lineStart, t.line)
+	t.out.Flush()
+	t.line = t.line[:0]
+}
+
+func (t *TTY) Flush() { //Write out a partial line
+	if len(t.line) > 0 {
+		t.emit()
+	}
+	t.out.Flush()
+}
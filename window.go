@@ -0,0 +1,114 @@
+//go:build cgo
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.6-core/gl"
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/Koops0/GPSXE/debugger"
+	"github.com/Koops0/GPSXE/gdbstub"
+	"github.com/Koops0/GPSXE/gpu"
+	"github.com/Koops0/GPSXE/gpu/glrender"
+	"github.com/Koops0/GPSXE/input"
+	"github.com/Koops0/GPSXE/input/sdlinput"
+	"github.com/Koops0/GPSXE/sio"
+	"github.com/Koops0/GPSXE/spu"
+	"github.com/Koops0/GPSXE/spu/sdlaudio"
+)
+
+// The SDL window, audio device and input, SDL needs cgo so builds without it are headless only
+
+// GL renderer in a new SDL window, the returned function shuts SDL down
+func Open_window() (gpu.Renderer, func(), error) {
+	if err := sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO | sdl.INIT_GAMECONTROLLER); err != nil {
+		return nil, nil, err
+	}
+
+	// Create an SDL renderer for the window
+	window := glrender.GLRenderer{}.New()
+	return &window, sdl.Quit, nil
+}
+
+func Open_audio(s *spu.SPU) { //The default device, the SPU stays silent without one
+	device, err := sdlaudio.NewSDLSink()
+	if err != nil {
+		fmt.Println("No audio output:", err)
+		return
+	}
+	s.SetSink(device)
+}
+
+// Runs a frame at a time with SDL events in between, until the window is closed
+func Run_windowed(cpu *CPU, dbg *debugger.Debugger, stub *gdbstub.Stub, bindings input.Config, pads [2]sio.Pad) int {
+	mapper, err := sdlinput.NewMapper(bindings, pads)
+	if err != nil {
+		fmt.Println("Error loading input bindings:", err)
+		return EXIT_ERROR
+	}
+	defer mapper.Close()
+
+	video := cpu.inter.Gpu()
+	for {
+		frame := video.Frames()
+		for video.Frames() == frame { //Until the GPU hands over the next frame
+			if stub != nil && stub.Quitting() {
+				return EXIT_OK
+			}
+			if dbg == nil {
+				cpu.Run_next()
+				continue
+			}
+			Run_guarded(cpu, dbg)
+			if dbg.Quitting() {
+				return EXIT_OK
+			}
+		}
+		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			switch event.(type) {
+			case *sdl.QuitEvent:
+				return EXIT_OK
+			default:
+				mapper.Handle(event)
+			}
+		}
+		mapper.Update()
+	}
+}
+
+func CheckForErrors() {
+	fatal := false
+
+	for {
+		buffer := make([]byte, 4096)
+		severity := uint32(0)
+		source := uint32(0)
+		mSize := int32(0)
+		mtype := uint32(0)
+		id := uint32(0)
+		count := gl.GetDebugMessageLog(1, int32(len(buffer)), &source, &mtype, &id, &severity, &mSize, &buffer[0])
+
+		if count == 0 {
+			break
+		}
+
+		// Assuming mSize is the actual message length, trim the buffer to mSize
+		message := string(buffer[:mSize])
+
+		fmt.Printf("OpenGL [source: %d | type: %d | id: 0x%x | severity: %d] %s\n", source, mtype, id, severity, message)
+
+		// Example severity check (adjust according to your severity values)
+		if severity == gl.DEBUG_SEVERITY_HIGH {
+			fatal = true
+			fmt.Println("Fatal OpenGL error encountered.")
+			break // or handle fatal error as needed
+		}
+	}
+
+	if fatal {
+		// Handle fatal error, e.g., clean up and exit or throw panic
+		panic("Fatal OpenGL error encountered.")
+	}
+}
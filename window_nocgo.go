@@ -0,0 +1,27 @@
+//go:build !cgo
+
+package main
+
+import (
+	"errors"
+
+	"github.com/Koops0/GPSXE/debugger"
+	"github.com/Koops0/GPSXE/gdbstub"
+	"github.com/Koops0/GPSXE/gpu"
+	"github.com/Koops0/GPSXE/input"
+	"github.com/Koops0/GPSXE/sio"
+	"github.com/Koops0/GPSXE/spu"
+)
+
+// Without cgo there's no SDL, only -headless runs
+
+func Open_window() (gpu.Renderer, func(), error) {
+	return nil, nil, errors.New("built without cgo, only -headless is available")
+}
+
+func Open_audio(s *spu.SPU) {
+}
+
+func Run_windowed(cpu *CPU, dbg *debugger.Debugger, stub *gdbstub.Stub, bindings input.Config, pads [2]sio.Pad) int {
+	return EXIT_ERROR
+}